@@ -1,49 +1,202 @@
 package backend
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"../util"
 )
 
-var Backend AnimationBackend
+var Backend = AnimationBackend{Settings: NewProjectSettings()}
+
+// TrackMain is the track of the camera selected for live view. Other tracks are numbered by device id.
+const TrackMain = -1
 
 type Frame struct {
 	Filename          string
 	ThumbnailFilename string
+
+	// the same moment seen by the other cameras of a multi-camera shoot
+	Angles []*Angle `json:",omitempty"`
+
+	// the camera frame before keying and the id of the settings it was rendered with in the project's Renders,
+	// missing for frames shot before raw frames were kept
+	RawFilename string `json:",omitempty"`
+	RenderID    string `json:",omitempty"`
+}
+
+// Angle is one frame captured by an additional camera
+type Angle struct {
+	DeviceID          int
+	Filename          string
+	ThumbnailFilename string
+	// the stream of a network camera, its device id is looked up again when the project is loaded
+	NetworkURL string `json:",omitempty"`
+}
+
+// Track returns the image and thumbnail of this frame on the given track. ok is false if the
+// frame was shot without that camera.
+func (f *Frame) Track(track int) (fileName string, thumbnailFileName string, ok bool) {
+	if track == TrackMain {
+		return f.Filename, f.ThumbnailFilename, true
+	}
+	for _, angle := range f.Angles {
+		if angle.DeviceID == track {
+			return angle.Filename, angle.ThumbnailFilename, true
+		}
+	}
+	return "", "", false
 }
 
 type AnimationBackend struct {
-	Name   string
-	Frames []*Frame
+	Name     string
+	Frames   []*Frame
+	Settings ProjectSettings
+	// the settings frames were rendered with by id, each stored once however many frames share it
+	Renders map[string]RenderSettings `json:",omitempty"`
+
+	// snapshots, renders in the background and saves change and write the frames from different goroutines
+	lock sync.Mutex
+	// the panels change the settings while the capture loop and snapshots read them
+	settingsLock sync.RWMutex
+}
+
+// CurrentSettings returns a copy of the project settings, for reading them outside the UI goroutine that changes them
+func (f *AnimationBackend) CurrentSettings() ProjectSettings {
+	f.settingsLock.RLock()
+	defer f.settingsLock.RUnlock()
+	return f.Settings
+}
+
+// UpdateSettings changes the project settings. Slices in them are replaced rather than changed in place, so the ones
+// in a copy returned by CurrentSettings stay as they were.
+func (f *AnimationBackend) UpdateSettings(update func(settings *ProjectSettings)) {
+	f.settingsLock.Lock()
+	defer f.settingsLock.Unlock()
+	update(&f.Settings)
+}
+
+// FrameList returns a copy of the list of frames, to work through while frames are added or removed
+func (f *AnimationBackend) FrameList() []*Frame {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]*Frame{}, f.Frames...)
+}
+
+// AddRender stores settings for the frames rendered with them and returns the id the frames refer to them by. The
+// id is derived from the settings, so frames rendered alike share one entry.
+func (f *AnimationBackend) AddRender(settings RenderSettings) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.addRender(settings)
+}
+
+func (f *AnimationBackend) addRender(settings RenderSettings) string {
+	bytes, err := json.Marshal(settings)
+	if err != nil {
+		log.Printf("error storing render settings: %s", err.Error())
+		return ""
+	}
+	sum := sha1.Sum(bytes)
+	id := hex.EncodeToString(sum[:8])
+	if f.Renders == nil {
+		f.Renders = make(map[string]RenderSettings)
+	}
+	f.Renders[id] = settings
+	return id
+}
+
+// FrameRender returns the settings frame was rendered with. ok is false if it can't be rendered again from its raw
+// camera frame.
+func (f *AnimationBackend) FrameRender(frame *Frame) (settings RenderSettings, ok bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if frame.RawFilename == "" {
+		return RenderSettings{}, false
+	}
+	settings, ok = f.Renders[frame.RenderID]
+	return settings, ok
+}
+
+// SetFrameRender records that frame was rendered again with settings
+func (f *AnimationBackend) SetFrameRender(frame *Frame, settings RenderSettings) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	frame.RenderID = f.addRender(settings)
+}
+
+// pruneRenders drops the render settings no frame refers to any more
+func (f *AnimationBackend) pruneRenders() {
+	used := make(map[string]bool)
+	for _, frame := range f.Frames {
+		used[frame.RenderID] = true
+	}
+	for id := range f.Renders {
+		if !used[id] {
+			delete(f.Renders, id)
+		}
+	}
+}
+
+// Tracks returns TrackMain followed by the device ids of every camera that shot an additional angle
+func (f *AnimationBackend) Tracks() []int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	deviceIDs := make([]int, 0)
+	for _, frame := range f.Frames {
+		for _, angle := range frame.Angles {
+			if !util.ContainsInt(deviceIDs, angle.DeviceID) {
+				deviceIDs = append(deviceIDs, angle.DeviceID)
+			}
+		}
+	}
+	sort.Ints(deviceIDs)
+	return append([]int{TrackMain}, deviceIDs...)
 }
 
 func (f *AnimationBackend) Append(frame *Frame) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	log.Printf("will insert at the end of %d frames", len(f.Frames))
 	f.Frames = append(f.Frames, frame)
 }
 
 func (f *AnimationBackend) InsertAt(index int, frame *Frame) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	log.Printf("will insert frame image %s at index %d", frame.Filename, index)
 	if len(f.Frames) == index {
-		f.Append(frame)
+		f.Frames = append(f.Frames, frame)
 		return
 	}
 	f.Frames = append(f.Frames[:index+1], f.Frames[index:]...)
 	f.Frames[index] = frame
+	f.UpdateSettings(func(settings *ProjectSettings) {
+		settings.CameraMove.frameInserted(index)
+	})
 }
 
 func (f *AnimationBackend) RemoveAt(index int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	log.Printf("will delete backend frame %d/%d", index, len(f.Frames))
 	f.Frames = append(f.Frames[:index], f.Frames[index+1:]...)
+	f.UpdateSettings(func(settings *ProjectSettings) {
+		settings.CameraMove.frameRemoved(index)
+	})
 }
 
 func (f *AnimationBackend) RemoveAll() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	log.Printf("clearing all frames from backend")
 	frames := make([]*Frame, 0)
 	f.Frames = frames
@@ -51,9 +204,14 @@ func (f *AnimationBackend) RemoveAll() {
 
 func (f *AnimationBackend) Save() error {
 	defer util.LogPerf("AnimationBackend.Save()", time.Now())
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	log.Printf("saving %d frames into project %s", len(f.Frames), f.Name)
 
+	f.pruneRenders()
+	f.settingsLock.RLock()
 	bytes, err := json.Marshal(f)
+	f.settingsLock.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -93,8 +251,29 @@ func (f *AnimationBackend) Load(fileName string) error {
 		return err
 	}
 
+	for _, frame := range newAnimation.Frames {
+		for _, angle := range frame.Angles {
+			if angle.NetworkURL == "" {
+				continue
+			}
+			deviceID, err := AddNetworkCamera(angle.NetworkURL)
+			if err != nil {
+				log.Printf("error restoring network camera angle: %s", err.Error())
+				continue
+			}
+			angle.DeviceID = deviceID
+		}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
 	f.Name = newAnimation.Name
 	f.Frames = newAnimation.Frames
+	f.Renders = newAnimation.Renders
+	newAnimation.Settings.applyDefaults()
+	f.UpdateSettings(func(settings *ProjectSettings) {
+		*settings = newAnimation.Settings
+	})
 
 	log.Printf("loaded %d frames into project %s", len(f.Frames), fileName)
 	return nil
@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"gocv.io/x/gocv"
+	"image"
+	"math"
+)
+
+// BackgroundFitMode is how a background of another size or aspect ratio is fitted to the frame
+type BackgroundFitMode int
+
+const (
+	// scaled to cover the frame, cropping what overhangs
+	BackgroundFill BackgroundFitMode = iota
+	// scaled to fit inside the frame, bordered with black
+	BackgroundFit
+	// scaled to the frame, ignoring the aspect ratio
+	BackgroundStretch
+	// shown at its own size in the middle of the frame
+	BackgroundCentre
+)
+
+var BackgroundFitModes = []BackgroundFitMode{BackgroundFill, BackgroundFit, BackgroundStretch, BackgroundCentre}
+
+func (m BackgroundFitMode) String() string {
+	switch m {
+	case BackgroundFit:
+		return "Fit"
+	case BackgroundStretch:
+		return "Stretch"
+	case BackgroundCentre:
+		return "Centre"
+	}
+	return "Fill"
+}
+
+// BackgroundSettings choose what keys composite the subject onto
+type BackgroundSettings struct {
+	// an image, the first image of a numbered sequence, or a video. Empty for a plain white background.
+	Path     string
+	Sequence bool
+	Fit      BackgroundFitMode
+	// sequences and videos start over after their last frame instead of holding it
+	Loop bool
+	// fractions of the frame size the background is moved by
+	OffsetX float64
+	OffsetY float64
+	// background frame shown for the first animation frame
+	FrameOffset int
+}
+
+// FitBackground fits a BGR background to a frame of the given size and moves it by the pan offsets
+func FitBackground(background gocv.Mat, size image.Point, settings BackgroundSettings, dst *gocv.Mat) {
+	width, height := float64(background.Cols()), float64(background.Rows())
+	scaleX, scaleY := float64(size.X)/width, float64(size.Y)/height
+	switch settings.Fit {
+	case BackgroundFill:
+		scaleX = math.Max(scaleX, scaleY)
+		scaleY = scaleX
+	case BackgroundFit:
+		scaleX = math.Min(scaleX, scaleY)
+		scaleY = scaleX
+	case BackgroundCentre:
+		scaleX, scaleY = 1, 1
+	}
+	scaledSize := image.Pt(int(math.Max(1, math.Round(width*scaleX))), int(math.Max(1, math.Round(height*scaleY))))
+	scaled := gocv.NewMat()
+	defer scaled.Close()
+	if scaledSize == image.Pt(background.Cols(), background.Rows()) {
+		background.CopyTo(&scaled)
+	} else {
+		gocv.Resize(background, &scaled, scaledSize, 0, 0, gocv.InterpolationLanczos4)
+	}
+
+	canvas := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), size.Y, size.X, gocv.MatTypeCV8UC3)
+	defer canvas.Close()
+	at := image.Pt((size.X-scaledSize.X)/2+int(math.Round(settings.OffsetX*float64(size.X))),
+		(size.Y-scaledSize.Y)/2+int(math.Round(settings.OffsetY*float64(size.Y))))
+	visible := image.Rectangle{Min: at, Max: at.Add(scaledSize)}.Intersect(image.Rect(0, 0, size.X, size.Y))
+	if !visible.Empty() {
+		source := scaled.Region(visible.Sub(at))
+		target := canvas.Region(visible)
+		source.CopyTo(&target)
+		source.Close()
+		target.Close()
+	}
+	canvas.CopyTo(dst)
+}
+
+// BackgroundFrame is the frame of a background with frameCount frames shown for an animation frame. Looping
+// backgrounds wrap around, others hold their last frame.
+func BackgroundFrame(index int, frameCount int, settings BackgroundSettings) int {
+	frame := index + settings.FrameOffset
+	if settings.Loop && frameCount > 0 {
+		frame = (frame%frameCount + frameCount) % frameCount
+	}
+	return frame
+}
+
+// RenderBackground fits the frame of source shown for animation frame index to a frame of the given size, or fills dst
+// with plain white if there is no source or it has no such frame
+func RenderBackground(source FrameSource, index int, settings BackgroundSettings, size image.Point, dst *gocv.Mat) {
+	frame := gocv.NewMat()
+	defer frame.Close()
+	if source != nil && source.Frame(BackgroundFrame(index, source.FrameCount(), settings), &frame) {
+		FitBackground(frame, size, settings, dst)
+		return
+	}
+	white := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255.0, 255.0, 255.0, 255.0), size.Y, size.X, gocv.MatTypeCV8UC3)
+	white.CopyTo(dst)
+	white.Close()
+}
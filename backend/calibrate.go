@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"errors"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+const (
+	// greys and darks are never part of a coloured backdrop
+	calibrationMinSaturation = 50
+	calibrationMinValue      = 40
+	// the backdrop has to cover at least this share of the frame
+	calibrationMinCoverage = 0.05
+	// share of the backdrop left out at each end of the tolerances, so sensor noise doesn't blow them up
+	calibrationOutliers = 0.02
+	// frames are analysed at this width, the backdrop is large and doesn't need every pixel
+	calibrationWidth = 320
+
+	// margins added to the measured spread, the key colour is rounded to 8 bits
+	calibrationHueMargin   = 2
+	calibrationLevelMargin = 10
+)
+
+var ErrNoBackdrop = errors.New("no coloured backdrop found, make sure the screen fills a good part of the frame")
+
+// HSVColor returns the colour with the given hue, saturation and value in OpenCV 8 bit units, the inverse of HSV
+func HSVColor(hue float64, saturation float64, value float64) color.RGBA {
+	h := math.Mod(hue*2, 360) / 60
+	chroma := value * saturation / 255
+	x := chroma * (1 - math.Abs(math.Mod(h, 2)-1))
+	var r, g, b float64
+	switch int(h) {
+	case 0:
+		r, g, b = chroma, x, 0
+	case 1:
+		r, g, b = x, chroma, 0
+	case 2:
+		r, g, b = 0, chroma, x
+	case 3:
+		r, g, b = 0, x, chroma
+	case 4:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	m := value - chroma
+	return color.RGBA{R: uint8(math.Round(r + m)), G: uint8(math.Round(g + m)), B: uint8(math.Round(b + m)), A: 255}
+}
+
+// hueOffset is the signed distance from origin to hue around the OpenCV hue circle, -90..89
+func hueOffset(hue float64, origin float64) float64 {
+	return math.Mod(hue-origin+270, 180) - 90
+}
+
+// percentile returns the value below which the given share of sorted values lies
+func percentile(sorted []float64, share float64) float64 {
+	return sorted[int(math.Round(share*float64(len(sorted)-1)))]
+}
+
+// spread is how far the values reach from center, ignoring outliers at both ends
+func spread(values []float64, center float64) float64 {
+	sorted := sortedCopy(values)
+	return math.Max(center-percentile(sorted, calibrationOutliers), percentile(sorted, 1-calibrationOutliers)-center)
+}
+
+// CalibrateKey finds the backdrop in a BGR frame, the largest cluster of similar saturated hues, and returns settings
+// whose key colour and tolerances cover it. The other settings are kept.
+func CalibrateKey(frame gocv.Mat, settings KeySettings) (KeySettings, error) {
+	small := gocv.NewMat()
+	defer small.Close()
+	height := frame.Rows() * calibrationWidth / frame.Cols()
+	gocv.Resize(frame, &small, image.Pt(calibrationWidth, height), 0, 0, gocv.InterpolationArea)
+	hsv := gocv.NewMat()
+	defer hsv.Close()
+	gocv.CvtColor(small, &hsv, gocv.ColorBGRToHSV)
+	return calibrateHSV(hsv.DataPtrUint8(), settings)
+}
+
+// calibrateHSV finds the backdrop in packed 8 bit HSV pixels for CalibrateKey
+func calibrateHSV(pixels []byte, settings KeySettings) (KeySettings, error) {
+	var histogram [180]int
+	for offset := 0; offset+2 < len(pixels); offset += 3 {
+		if pixels[offset+1] >= calibrationMinSaturation && pixels[offset+2] >= calibrationMinValue {
+			histogram[pixels[offset]%180]++
+		}
+	}
+	// smoothed around the hue circle, so a backdrop straddling two bins still peaks
+	var smoothed [180]int
+	peak := 0
+	for hue := range smoothed {
+		for delta := -3; delta <= 3; delta++ {
+			smoothed[hue] += histogram[(hue+delta+180)%180]
+		}
+		if smoothed[hue] > smoothed[peak] {
+			peak = hue
+		}
+	}
+	if smoothed[peak] == 0 {
+		return settings, ErrNoBackdrop
+	}
+	// the cluster reaches out from the peak until the histogram falls off
+	low, high := 0, 0
+	for low > -45 && smoothed[(peak+low-1+180)%180] > smoothed[peak]/20 {
+		low--
+	}
+	for high < 45 && smoothed[(peak+high+1)%180] > smoothed[peak]/20 {
+		high++
+	}
+
+	hues, saturations, values := make([]float64, 0), make([]float64, 0), make([]float64, 0)
+	for offset := 0; offset+2 < len(pixels); offset += 3 {
+		if pixels[offset+1] < calibrationMinSaturation || pixels[offset+2] < calibrationMinValue {
+			continue
+		}
+		hue := hueOffset(float64(pixels[offset]), float64(peak))
+		if hue < float64(low) || hue > float64(high) {
+			continue
+		}
+		hues = append(hues, hue)
+		saturations = append(saturations, float64(pixels[offset+1]))
+		values = append(values, float64(pixels[offset+2]))
+	}
+	if float64(len(hues)) < calibrationMinCoverage*float64(len(pixels)/3) {
+		return settings, ErrNoBackdrop
+	}
+
+	hue := percentile(sortedCopy(hues), 0.5)
+	saturation := percentile(sortedCopy(saturations), 0.5)
+	value := percentile(sortedCopy(values), 0.5)
+	settings.Color = HSVColor(math.Mod(float64(peak)+hue+180, 180), saturation, value)
+	settings.HueTolerance = math.Min(spread(hues, hue)+calibrationHueMargin, 90)
+	settings.SaturationTolerance = math.Min(spread(saturations, saturation)+calibrationLevelMargin, 255)
+	settings.ValueTolerance = math.Min(spread(values, value)+calibrationLevelMargin, 255)
+	return settings, nil
+}
+
+// SampleKey keys the average colour of region of a BGR frame, widening the tolerances where they don't cover every
+// colour in it
+func SampleKey(frame gocv.Mat, region image.Rectangle, settings KeySettings) KeySettings {
+	region = region.Intersect(image.Rect(0, 0, frame.Cols(), frame.Rows()))
+	if region.Empty() {
+		return settings
+	}
+	roi := frame.Region(region)
+	defer roi.Close()
+	sample := roi.Clone() // continuous
+	defer sample.Close()
+	hsv := gocv.NewMat()
+	defer hsv.Close()
+	gocv.CvtColor(sample, &hsv, gocv.ColorBGRToHSV)
+	return sampleKey(sample.DataPtrUint8(), hsv.DataPtrUint8(), settings)
+}
+
+// sampleKey keys the average of packed 8 bit BGR pixels for SampleKey, hsvPixels are the same pixels in HSV
+func sampleKey(pixels []byte, hsvPixels []byte, settings KeySettings) KeySettings {
+	var sum [3]float64
+	for offset := 0; offset+2 < len(pixels); offset += 3 {
+		for channel := range sum {
+			sum[channel] += float64(pixels[offset+channel])
+		}
+	}
+	count := float64(len(pixels) / 3)
+	settings.Color = color.RGBA{R: uint8(math.Round(sum[2] / count)), G: uint8(math.Round(sum[1] / count)), B: uint8(math.Round(sum[0] / count)), A: 255}
+	if count < 2 {
+		return settings
+	}
+
+	hue, saturation, value := HSV(settings.Color)
+	hues, saturations, values := make([]float64, 0), make([]float64, 0), make([]float64, 0)
+	pixels = hsvPixels
+	for offset := 0; offset+2 < len(pixels); offset += 3 {
+		hues = append(hues, hueOffset(float64(pixels[offset]), hue))
+		saturations = append(saturations, float64(pixels[offset+1]))
+		values = append(values, float64(pixels[offset+2]))
+	}
+	settings.HueTolerance = math.Max(settings.HueTolerance, math.Min(spread(hues, 0)+calibrationHueMargin, 90))
+	settings.SaturationTolerance = math.Max(settings.SaturationTolerance, math.Min(spread(saturations, saturation)+calibrationLevelMargin, 255))
+	settings.ValueTolerance = math.Max(settings.ValueTolerance, math.Min(spread(values, value)+calibrationLevelMargin, 255))
+	return settings
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	return sorted
+}
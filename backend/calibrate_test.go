@@ -0,0 +1,145 @@
+package backend
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// pixels returns count packed 3 channel pixels of the given channel values
+func pixels(count int, a uint8, b uint8, c uint8) []byte {
+	packed := make([]byte, 0, count*3)
+	for i := 0; i < count; i++ {
+		packed = append(packed, a, b, c)
+	}
+	return packed
+}
+
+func TestHSVColorRoundTrip(t *testing.T) {
+	for hue := 0.0; hue < 180; hue += 5 {
+		for _, saturation := range []float64{255, 160, 80} {
+			for _, value := range []float64{255, 160, 80} {
+				h, s, v := HSV(HSVColor(hue, saturation, value))
+				// the colour is rounded to 8 bits, which moves dark, pale hues the most
+				if math.Abs(hueOffset(h, hue)) > 1.5 || math.Abs(s-saturation) > 3 || math.Abs(v-value) > 1 {
+					t.Errorf("HSV(HSVColor(%.0f, %.0f, %.0f)) = %.1f, %.1f, %.1f", hue, saturation, value, h, s, v)
+				}
+			}
+		}
+	}
+	if c := HSVColor(60, 255, 255); c != (color.RGBA{G: 255, A: 255}) {
+		t.Errorf("green is %v", c)
+	}
+	if c := HSVColor(0, 0, 128); c != (color.RGBA{R: 128, G: 128, B: 128, A: 255}) {
+		t.Errorf("grey is %v", c)
+	}
+}
+
+func TestHueOffset(t *testing.T) {
+	tests := []struct {
+		hue    float64
+		origin float64
+		want   float64
+	}{
+		{60, 60, 0},
+		{70, 60, 10},
+		{50, 60, -10},
+		{5, 175, 10},
+		{175, 5, -10},
+		{179, 0, -1},
+		{0, 179, 1},
+		{89, 0, 89},
+		{90, 0, -90},
+	}
+	for _, test := range tests {
+		if offset := hueOffset(test.hue, test.origin); offset != test.want {
+			t.Errorf("hueOffset(%.0f, %.0f) = %.0f, want %.0f", test.hue, test.origin, offset, test.want)
+		}
+	}
+}
+
+func TestCalibrateHSV(t *testing.T) {
+	// a red backdrop straddling the end of the hue circle, with some grey and a smaller blue patch
+	frame := make([]byte, 0)
+	for _, hue := range []uint8{176, 177, 178, 179, 0, 1, 2, 3, 4} {
+		frame = append(frame, pixels(50, hue, 200, 180)...)
+	}
+	frame = append(frame, pixels(200, 0, 0, 128)...)
+	frame = append(frame, pixels(100, 120, 255, 255)...)
+
+	settings, err := calibrateHSV(frame, DefaultKeySettings)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	hue, saturation, value := HSV(settings.Color)
+	if math.Abs(hueOffset(hue, 0)) > 1.5 || math.Abs(saturation-200) > 3 || math.Abs(value-180) > 1 {
+		t.Errorf("key colour %.1f, %.1f, %.1f, want about 0, 200, 180", hue, saturation, value)
+	}
+	if settings.HueTolerance < 4+calibrationHueMargin || settings.HueTolerance > 6+calibrationHueMargin {
+		t.Errorf("hue tolerance %.1f doesn't fit the backdrop's 176..4", settings.HueTolerance)
+	}
+	if settings.SaturationTolerance != calibrationLevelMargin || settings.ValueTolerance != calibrationLevelMargin {
+		t.Errorf("level tolerances %.1f, %.1f, want %d for a flat backdrop", settings.SaturationTolerance, settings.ValueTolerance, calibrationLevelMargin)
+	}
+	if settings.Softness != DefaultKeySettings.Softness || settings.SpillSuppression != DefaultKeySettings.SpillSuppression {
+		t.Error("calibrating changed settings other than the colour and tolerances")
+	}
+}
+
+func TestCalibrateHSVCoverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		backdrop int
+		ok       bool
+	}{
+		{"no backdrop", 0, false},
+		{"below the threshold", 4, false},
+		{"on the threshold", 5, true},
+		{"above the threshold", 6, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frame := append(pixels(test.backdrop, 60, 255, 255), pixels(100-test.backdrop, 60, 20, 255)...)
+			_, err := calibrateHSV(frame, DefaultKeySettings)
+			if test.ok && err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+			if !test.ok && err != ErrNoBackdrop {
+				t.Errorf("error %v, want ErrNoBackdrop", err)
+			}
+		})
+	}
+}
+
+func TestSampleKey(t *testing.T) {
+	settings := KeySettings{HueTolerance: 1, SaturationTolerance: 1, ValueTolerance: 1, Softness: 3}
+	// two greens of different brightness, BGR
+	sample := append(pixels(10, 0, 200, 0), pixels(10, 0, 240, 0)...)
+	hsv := append(pixels(10, 60, 255, 200), pixels(10, 60, 255, 240)...)
+	sampled := sampleKey(sample, hsv, settings)
+	if sampled.Color != (color.RGBA{G: 220, A: 255}) {
+		t.Errorf("key colour %v, want the average green", sampled.Color)
+	}
+	if sampled.HueTolerance != calibrationHueMargin || sampled.SaturationTolerance != calibrationLevelMargin {
+		t.Errorf("tolerances %.1f, %.1f, want the margins", sampled.HueTolerance, sampled.SaturationTolerance)
+	}
+	if sampled.ValueTolerance != 20+calibrationLevelMargin {
+		t.Errorf("value tolerance %.1f, want %d to cover both greens", sampled.ValueTolerance, 20+calibrationLevelMargin)
+	}
+	if sampled.Softness != 3 {
+		t.Error("sampling changed the softness")
+	}
+
+	// tolerances are widened, never narrowed
+	settings = KeySettings{HueTolerance: 30, SaturationTolerance: 100, ValueTolerance: 100}
+	sampled = sampleKey(sample, hsv, settings)
+	if sampled.HueTolerance != 30 || sampled.SaturationTolerance != 100 || sampled.ValueTolerance != 100 {
+		t.Errorf("tolerances narrowed to %.1f, %.1f, %.1f", sampled.HueTolerance, sampled.SaturationTolerance, sampled.ValueTolerance)
+	}
+
+	// a single pixel only sets the colour
+	sampled = sampleKey(pixels(1, 255, 0, 0), pixels(1, 120, 255, 255), settings)
+	if sampled.Color != (color.RGBA{B: 255, A: 255}) || sampled.HueTolerance != 30 {
+		t.Errorf("single pixel sample %+v", sampled)
+	}
+}
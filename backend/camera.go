@@ -1,35 +1,364 @@
 package backend
 
 import (
+	"fmt"
 	"gocv.io/x/gocv"
+	"image"
 	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	cameraReconnectInterval = 2 * time.Second
 
-	"../config"
+	// how many frames a webcam driver typically queues
+	bufferedFrames = 4
 )
 
 var (
 	Cameras = map[int]*gocv.VideoCapture{}
+
+	// guards the camera maps. operations on an open camera are serialized by its own lock in captureLocks,
+	// so several cameras can be read at the same time
+	cameraLock   sync.Mutex
+	captureLocks = map[*gocv.VideoCapture]*sync.Mutex{}
+
+	// devices that were lost and are being reopened in the background
+	reconnecting = map[int]bool{}
 )
 
 var CurrentWebcamID = -1
 
 func CurrentCamera() *gocv.VideoCapture {
+	cameraLock.Lock()
+	defer cameraLock.Unlock()
 	return Cameras[CurrentWebcamID]
 }
 
 func SwitchCamera(deviceID int) (*gocv.VideoCapture, int, error) {
 	if deviceID == CurrentWebcamID {
-		return Cameras[CurrentWebcamID], CurrentWebcamID, nil
+		return CurrentCamera(), CurrentWebcamID, nil
 	}
-	webcam, err := gocv.OpenVideoCapture(deviceID)
-	if err != nil {
-		log.Printf("error opening new webcam %d. reopening previous %d", deviceID, CurrentWebcamID)
-		return Cameras[CurrentWebcamID], CurrentWebcamID, err
+	webcam := openedCamera(deviceID)
+	if webcam == nil {
+		var err error
+		webcam, err = openCamera(deviceID)
+		if err != nil {
+			log.Printf("error opening new webcam %d. reopening previous %d", deviceID, CurrentWebcamID)
+			return CurrentCamera(), CurrentWebcamID, err
+		}
+		log.Printf("opened cam %d", deviceID)
 	}
-	log.Printf("opened cam %d", deviceID)
+	cameraLock.Lock()
 	CurrentWebcamID = deviceID
 	Cameras[CurrentWebcamID] = webcam
-	webcam.Set(gocv.VideoCaptureFrameWidth, config.WebcamCaptureWidth)
-	webcam.Set(gocv.VideoCaptureFrameHeight, config.WebcamCaptureHeight)
+	cameraLock.Unlock()
+	setCameraResolution(webcam, CurrentResolution())
+	ApplyCameraSettings(webcam, Backend.CurrentSettings().Camera)
 	return webcam, CurrentWebcamID, nil
 }
+
+func openedCamera(deviceID int) *gocv.VideoCapture {
+	cameraLock.Lock()
+	defer cameraLock.Unlock()
+	return Cameras[deviceID]
+}
+
+// openCamera opens a numbered local device or a registered network stream
+func openCamera(deviceID int) (*gocv.VideoCapture, error) {
+	streamURL := NetworkCameraURL(deviceID)
+	if streamURL == "" {
+		return gocv.OpenVideoCapture(deviceID)
+	}
+	webcam, err := gocv.OpenVideoCapture(streamURL)
+	if err != nil {
+		return nil, err
+	}
+	webcam.Set(gocv.VideoCaptureBufferSize, 1) // always read the newest frame instead of a queue of stale ones
+	return webcam, nil
+}
+
+// lockCapture serializes reads and setting changes on one camera. Use as defer lockCapture(webcam)()
+func lockCapture(webcam *gocv.VideoCapture) func() {
+	cameraLock.Lock()
+	captureLock := captureLocks[webcam]
+	if captureLock == nil {
+		captureLock = &sync.Mutex{}
+		captureLocks[webcam] = captureLock
+	}
+	cameraLock.Unlock()
+	captureLock.Lock()
+	return captureLock.Unlock
+}
+
+// ReadCamera reads the next frame from an open camera
+func ReadCamera(deviceID int, mat *gocv.Mat) bool {
+	webcam := openedCamera(deviceID)
+	if webcam == nil {
+		return false
+	}
+	unlock := lockCapture(webcam)
+	startTime := time.Now()
+	ok := webcam.Read(mat)
+	unlock()
+
+	cameraLock.Lock()
+	recordReadLatency(deviceID, time.Since(startTime))
+	cameraLock.Unlock()
+	return ok
+}
+
+// ReadCameras reads a fresh frame from each camera at the same time, conformed to the project resolution.
+// Cameras that fail to deliver are left out of the result. The caller must close the returned frames.
+func ReadCameras(deviceIDs []int) map[int]*gocv.Mat {
+	frames := map[int]*gocv.Mat{}
+	framesLock := sync.Mutex{}
+	waitGroup := sync.WaitGroup{}
+	for _, deviceID := range deviceIDs {
+		waitGroup.Add(1)
+		go func(deviceID int) {
+			defer waitGroup.Done()
+			webcam := openedCamera(deviceID)
+			if webcam == nil {
+				return
+			}
+			unlock := lockCapture(webcam)
+			webcam.Grab(bufferedFrames) // drop frames the driver queued while nobody was reading this camera
+			unlock()
+
+			frame := gocv.NewMat()
+			if !ReadCamera(deviceID, &frame) || frame.Empty() {
+				log.Printf("no frame from cam %d", deviceID)
+				frame.Close()
+				return
+			}
+			ConformToResolution(&frame, CurrentResolution())
+			framesLock.Lock()
+			frames[deviceID] = &frame
+			framesLock.Unlock()
+		}(deviceID)
+	}
+	waitGroup.Wait()
+	return frames
+}
+
+// OpenCameraIDs returns the device ids of all open cameras in ascending order
+func OpenCameraIDs() []int {
+	cameraLock.Lock()
+	defer cameraLock.Unlock()
+	deviceIDs := make([]int, 0, len(Cameras))
+	for deviceID := range Cameras {
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	sort.Ints(deviceIDs)
+	return deviceIDs
+}
+
+// ReopenCamera closes deviceID and opens it again with the project's resolution and manual settings
+func ReopenCamera(deviceID int) error {
+	if webcam := openedCamera(deviceID); webcam != nil {
+		unlock := lockCapture(webcam)
+		webcam.Close()
+		unlock()
+		cameraLock.Lock()
+		delete(Cameras, deviceID)
+		delete(captureLocks, webcam)
+		cameraLock.Unlock()
+	}
+	webcam, err := openCamera(deviceID)
+	if err != nil {
+		return err
+	}
+	testMat := gocv.NewMat()
+	ok := webcam.Read(&testMat)
+	testMat.Close()
+	if !ok {
+		webcam.Close()
+		return fmt.Errorf("cam %d opened but returns no frames", deviceID)
+	}
+	cameraLock.Lock()
+	Cameras[deviceID] = webcam
+	cameraLock.Unlock()
+
+	log.Printf("reopened cam %d", deviceID)
+	setCameraResolution(webcam, CurrentResolution())
+	ApplyCameraSettings(webcam, Backend.CurrentSettings().Camera)
+	return nil
+}
+
+// ReconnectCamera keeps trying to reopen a lost camera in the background until it comes back.
+// onReconnected is called once the device delivers frames again.
+func ReconnectCamera(deviceID int, onReconnected func()) {
+	cameraLock.Lock()
+	if reconnecting[deviceID] {
+		cameraLock.Unlock()
+		return
+	}
+	reconnecting[deviceID] = true
+	cameraLock.Unlock()
+
+	log.Printf("lost cam %d. will try to reconnect every %s", deviceID, cameraReconnectInterval)
+	go func() {
+		for attempt := 1; ; attempt++ {
+			time.Sleep(cameraReconnectInterval)
+			err := ReopenCamera(deviceID)
+			if err != nil {
+				log.Printf("reconnect attempt %d for cam %d failed: %s", attempt, deviceID, err.Error())
+				continue
+			}
+			cameraLock.Lock()
+			delete(reconnecting, deviceID)
+			cameraLock.Unlock()
+			onReconnected()
+			return
+		}
+	}()
+}
+
+func IsReconnecting(deviceID int) bool {
+	cameraLock.Lock()
+	defer cameraLock.Unlock()
+	return reconnecting[deviceID]
+}
+
+// ApplyResolution requests the project's capture resolution from every open camera
+func ApplyResolution() {
+	res := CurrentResolution()
+	cameraLock.Lock()
+	webcams := make(map[int]*gocv.VideoCapture, len(Cameras))
+	for deviceID, webcam := range Cameras {
+		webcams[deviceID] = webcam
+	}
+	cameraLock.Unlock()
+	for deviceID, webcam := range webcams {
+		log.Printf("setting cam %d to %dx%d", deviceID, res.Width, res.Height)
+		setCameraResolution(webcam, res)
+	}
+}
+
+func setCameraResolution(webcam *gocv.VideoCapture, res Resolution) {
+	defer lockCapture(webcam)()
+	webcam.Set(gocv.VideoCaptureFrameWidth, float64(res.Width))
+	webcam.Set(gocv.VideoCaptureFrameHeight, float64(res.Height))
+}
+
+// ConformToResolution center-crops mat to the aspect ratio of res and scales it to exactly res.
+// Cameras don't support every resolution, e.g. 1:1, so frames are conformed after reading.
+func ConformToResolution(mat *gocv.Mat, res Resolution) {
+	width, height := mat.Cols(), mat.Rows()
+	if width == res.Width && height == res.Height {
+		return
+	}
+	cropWidth, cropHeight := res.FitInto(width, height)
+	xOffset := (width - cropWidth) / 2
+	yOffset := (height - cropHeight) / 2
+	cropped := mat.Region(image.Rect(xOffset, yOffset, xOffset+cropWidth, yOffset+cropHeight))
+	defer cropped.Close()
+	conformed := gocv.NewMat()
+	defer conformed.Close()
+	gocv.Resize(cropped, &conformed, res.Point(), 0, 0, gocv.InterpolationLinear)
+	conformed.CopyTo(mat)
+}
+
+// the values OpenCV expects for VideoCaptureAutoExposure
+const (
+	autoExposureOn     = 0.75
+	autoExposureManual = 0.25
+)
+
+// CameraSettings are manual camera controls. Webcams continuously re-adjust exposure, focus and white balance,
+// which makes stop-motion footage flicker, so these can be fixed for the whole session.
+type CameraSettings struct {
+	// Enabled is false until a control is touched; untouched projects leave the camera at its own defaults
+	Enabled bool
+	// Locked turns every automatic adjustment off
+	Locked bool
+
+	AutoExposure     bool
+	Exposure         float64
+	Gain             float64
+	AutoFocus        bool
+	Focus            float64
+	AutoWhiteBalance bool
+	WhiteBalance     float64 // color temperature in kelvin
+	Brightness       float64
+	Contrast         float64
+}
+
+// ReadCameraSettings returns the values the camera is currently using
+func ReadCameraSettings(webcam *gocv.VideoCapture) CameraSettings {
+	defer lockCapture(webcam)()
+	return CameraSettings{
+		AutoExposure:     webcam.Get(gocv.VideoCaptureAutoExposure) > autoExposureManual,
+		Exposure:         webcam.Get(gocv.VideoCaptureExposure),
+		Gain:             webcam.Get(gocv.VideoCaptureGain),
+		AutoFocus:        webcam.Get(gocv.VideoCaptureAutoFocus) > 0,
+		Focus:            webcam.Get(gocv.VideoCaptureFocus),
+		AutoWhiteBalance: webcam.Get(gocv.VideoCaptureAutoWB) > 0,
+		WhiteBalance:     webcam.Get(gocv.VideoCaptureWBTemperature),
+		Brightness:       webcam.Get(gocv.VideoCaptureBrightness),
+		Contrast:         webcam.Get(gocv.VideoCaptureContrast),
+	}
+}
+
+// ApplyCameraSettings sends settings to webcam. Manual values are only sent for controls that are not on auto,
+// otherwise the camera would switch that control back to manual.
+func ApplyCameraSettings(webcam *gocv.VideoCapture, settings CameraSettings) {
+	if webcam == nil || !settings.Enabled {
+		return
+	}
+	defer lockCapture(webcam)()
+
+	if settings.AutoExposure && !settings.Locked {
+		webcam.Set(gocv.VideoCaptureAutoExposure, autoExposureOn)
+	} else {
+		webcam.Set(gocv.VideoCaptureAutoExposure, autoExposureManual)
+		webcam.Set(gocv.VideoCaptureExposure, settings.Exposure)
+		webcam.Set(gocv.VideoCaptureGain, settings.Gain)
+	}
+
+	if settings.AutoFocus && !settings.Locked {
+		webcam.Set(gocv.VideoCaptureAutoFocus, 1)
+	} else {
+		webcam.Set(gocv.VideoCaptureAutoFocus, 0)
+		webcam.Set(gocv.VideoCaptureFocus, settings.Focus)
+	}
+
+	if settings.AutoWhiteBalance && !settings.Locked {
+		webcam.Set(gocv.VideoCaptureAutoWB, 1)
+	} else {
+		webcam.Set(gocv.VideoCaptureAutoWB, 0)
+		webcam.Set(gocv.VideoCaptureWBTemperature, settings.WhiteBalance)
+	}
+
+	webcam.Set(gocv.VideoCaptureBrightness, settings.Brightness)
+	webcam.Set(gocv.VideoCaptureContrast, settings.Contrast)
+}
+
+// UpdateCameraSettings changes the project's camera settings and applies them to the current camera.
+// The first change seeds the settings from the camera so untouched controls keep their current values.
+func UpdateCameraSettings(update func(settings *CameraSettings)) {
+	// the camera is read before taking the settings lock, which the capture loop waits on
+	var seed *CameraSettings
+	if !Backend.CurrentSettings().Camera.Enabled {
+		webcam := CurrentCamera()
+		if webcam == nil {
+			log.Printf("no camera to read settings from")
+			return
+		}
+		cameraSettings := ReadCameraSettings(webcam)
+		seed = &cameraSettings
+	}
+	var settings CameraSettings
+	Backend.UpdateSettings(func(projectSettings *ProjectSettings) {
+		if !projectSettings.Camera.Enabled && seed != nil {
+			projectSettings.Camera = *seed
+			projectSettings.Camera.Enabled = true
+		}
+		update(&projectSettings.Camera)
+		settings = projectSettings.Camera
+	})
+	ApplyCameraSettings(CurrentCamera(), settings)
+}
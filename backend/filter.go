@@ -0,0 +1,344 @@
+package backend
+
+import (
+	"encoding/binary"
+	"gocv.io/x/gocv"
+	"image"
+	"math"
+	"sync"
+)
+
+// FilterType names one of the built-in image filters
+type FilterType string
+
+const (
+	FilterBrightnessContrast FilterType = "Brightness/Contrast"
+	FilterSaturation         FilterType = "Saturation"
+	FilterGamma              FilterType = "Gamma"
+	FilterSepia              FilterType = "Sepia"
+	FilterBlackAndWhite      FilterType = "Black & White"
+	FilterVignette           FilterType = "Vignette"
+	FilterSharpen            FilterType = "Sharpen"
+	FilterBlur               FilterType = "Blur"
+)
+
+// FilterTypes are the filters offered to the user, in menu order
+var FilterTypes = []FilterType{FilterBrightnessContrast, FilterSaturation, FilterGamma, FilterSepia,
+	FilterBlackAndWhite, FilterVignette, FilterSharpen, FilterBlur}
+
+// FilterParam is an adjustable parameter of a filter
+type FilterParam struct {
+	Name    string
+	Min     float64
+	Max     float64
+	Step    float64
+	Default float64
+}
+
+// Filter changes a BGR frame in place. Sizes are given in pixels of the captured frame, so filters have to scale
+// them to frames of other sizes such as the live view.
+type Filter interface {
+	Params() []FilterParam
+	Apply(frame *gocv.Mat, params map[string]float64)
+}
+
+var filters = map[FilterType]Filter{
+	FilterBrightnessContrast: brightnessContrastFilter{},
+	FilterSaturation:         saturationFilter{},
+	FilterGamma:              gammaFilter{},
+	FilterSepia:              sepiaFilter{},
+	FilterBlackAndWhite:      blackAndWhiteFilter{},
+	FilterVignette:           &vignetteFilter{},
+	FilterSharpen:            sharpenFilter{},
+	FilterBlur:               blurFilter{},
+}
+
+// FilterSettings is one step of the project's filter chain
+type FilterSettings struct {
+	Type    FilterType
+	Enabled bool
+	Params  map[string]float64
+}
+
+// NewFilterSettings returns an enabled filter of the given type with default parameters
+func NewFilterSettings(filterType FilterType) FilterSettings {
+	settings := FilterSettings{Type: filterType, Enabled: true, Params: map[string]float64{}}
+	for _, param := range FilterParams(filterType) {
+		settings.Params[param.Name] = param.Default
+	}
+	return settings
+}
+
+func FilterParams(filterType FilterType) []FilterParam {
+	filter, ok := filters[filterType]
+	if !ok {
+		return nil
+	}
+	return filter.Params()
+}
+
+// Param returns the value of the named parameter, or its default when the project doesn't have it
+func (s FilterSettings) Param(name string) float64 {
+	if value, ok := s.Params[name]; ok {
+		return value
+	}
+	for _, param := range FilterParams(s.Type) {
+		if param.Name == name {
+			return param.Default
+		}
+	}
+	return 0
+}
+
+// WithParam returns a copy of the settings with the named parameter changed. The capture loop reads the
+// parameters while the UI changes them, so they are never modified in place.
+func (s FilterSettings) WithParam(name string, value float64) FilterSettings {
+	params := map[string]float64{}
+	for paramName, paramValue := range s.Params {
+		params[paramName] = paramValue
+	}
+	params[name] = value
+	s.Params = params
+	return s
+}
+
+// ApplyFilters runs the enabled filters of chain over frame in order
+func ApplyFilters(chain []FilterSettings, frame *gocv.Mat) {
+	for _, settings := range chain {
+		if !settings.Enabled {
+			continue
+		}
+		filter, ok := filters[settings.Type]
+		if !ok {
+			continue
+		}
+		params := map[string]float64{}
+		for _, param := range filter.Params() {
+			params[param.Name] = settings.Param(param.Name)
+		}
+		filter.Apply(frame, params)
+	}
+}
+
+// frameScale is how much smaller frame is than a captured frame
+func frameScale(frame *gocv.Mat) float64 {
+	width := CurrentResolution().Width
+	if width <= 0 {
+		return 1
+	}
+	return float64(frame.Cols()) / float64(width)
+}
+
+// applyToneCurve maps every channel value of frame through curve, which takes and returns values in 0..255
+func applyToneCurve(frame *gocv.Mat, curve func(value float64) float64) {
+	table := make([]byte, 256)
+	for value := range table {
+		table[value] = byte(math.Max(0, math.Min(255, math.Round(curve(float64(value))))))
+	}
+	lut, err := gocv.NewMatFromBytes(1, 256, gocv.MatTypeCV8U, table)
+	if err != nil {
+		return
+	}
+	defer lut.Close()
+	gocv.LUT(*frame, lut, frame)
+}
+
+// blendGray mixes frame with its greyscale version, amount 0 leaves it grey and values above 1 boost the colour
+func blendGray(frame *gocv.Mat, amount float64) {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(*frame, &gray, gocv.ColorBGRToGray)
+	grayBgr := gocv.NewMat()
+	defer grayBgr.Close()
+	gocv.CvtColor(gray, &grayBgr, gocv.ColorGrayToBGR)
+	gocv.AddWeighted(*frame, amount, grayBgr, 1-amount, 0, frame)
+}
+
+type brightnessContrastFilter struct{}
+
+func (brightnessContrastFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Name: "Brightness", Min: -100, Max: 100, Step: 1},
+		{Name: "Contrast", Min: -100, Max: 100, Step: 1},
+	}
+}
+
+func (brightnessContrastFilter) Apply(frame *gocv.Mat, params map[string]float64) {
+	brightness := params["Brightness"] / 100 * 255
+	contrast := 1 + params["Contrast"]/100
+	applyToneCurve(frame, func(value float64) float64 {
+		return (value-128)*contrast + 128 + brightness
+	})
+}
+
+type saturationFilter struct{}
+
+func (saturationFilter) Params() []FilterParam {
+	return []FilterParam{{Name: "Saturation", Min: 0, Max: 200, Step: 5, Default: 100}}
+}
+
+func (saturationFilter) Apply(frame *gocv.Mat, params map[string]float64) {
+	blendGray(frame, params["Saturation"]/100)
+}
+
+type gammaFilter struct{}
+
+func (gammaFilter) Params() []FilterParam {
+	return []FilterParam{{Name: "Gamma", Min: 0.2, Max: 3, Step: 0.05, Default: 1}}
+}
+
+func (gammaFilter) Apply(frame *gocv.Mat, params map[string]float64) {
+	gamma := math.Max(params["Gamma"], 0.01)
+	applyToneCurve(frame, func(value float64) float64 {
+		return 255 * math.Pow(value/255, 1/gamma)
+	})
+}
+
+type sepiaFilter struct{}
+
+// the usual sepia matrix, rows and columns in BGR order
+var sepiaMatrix = [3][3]float32{
+	{0.131, 0.534, 0.272},
+	{0.168, 0.686, 0.349},
+	{0.189, 0.769, 0.393},
+}
+
+func (sepiaFilter) Params() []FilterParam {
+	return []FilterParam{{Name: "Strength", Min: 0, Max: 100, Step: 5, Default: 100}}
+}
+
+func (sepiaFilter) Apply(frame *gocv.Mat, params map[string]float64) {
+	strength := params["Strength"] / 100
+	matrix := gocv.NewMatWithSize(3, 3, gocv.MatTypeCV32F)
+	defer matrix.Close()
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			identity := float32(0)
+			if row == col {
+				identity = 1
+			}
+			matrix.SetFloatAt(row, col, identity+(sepiaMatrix[row][col]-identity)*float32(strength))
+		}
+	}
+	toned := gocv.NewMat()
+	defer toned.Close()
+	gocv.Transform(*frame, &toned, matrix)
+	toned.CopyTo(frame)
+}
+
+type blackAndWhiteFilter struct{}
+
+func (blackAndWhiteFilter) Params() []FilterParam {
+	return []FilterParam{{Name: "Strength", Min: 0, Max: 100, Step: 5, Default: 100}}
+}
+
+func (blackAndWhiteFilter) Apply(frame *gocv.Mat, params map[string]float64) {
+	blendGray(frame, 1-params["Strength"]/100)
+}
+
+// vignetteFilter keeps the masks it built, since they only change with the frame size and parameters
+type vignetteFilter struct {
+	lock  sync.Mutex
+	masks map[vignetteKey]gocv.Mat
+}
+
+type vignetteKey struct {
+	size   image.Point
+	amount float64
+	radius float64
+}
+
+// the live view and snapshots use masks of different sizes
+const maxVignetteMasks = 4
+
+func (*vignetteFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Name: "Amount", Min: 0, Max: 100, Step: 5, Default: 50},
+		{Name: "Radius", Min: 0, Max: 90, Step: 5, Default: 50},
+	}
+}
+
+func (f *vignetteFilter) Apply(frame *gocv.Mat, params map[string]float64) {
+	key := vignetteKey{size: image.Pt(frame.Cols(), frame.Rows()), amount: params["Amount"] / 100, radius: params["Radius"] / 100}
+	if key.amount <= 0 || key.size.X == 0 || key.size.Y == 0 {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	mask, ok := f.masks[key]
+	if !ok {
+		if f.masks == nil || len(f.masks) >= maxVignetteMasks {
+			for _, oldMask := range f.masks {
+				oldMask.Close()
+			}
+			f.masks = map[vignetteKey]gocv.Mat{}
+		}
+		mask = newVignetteMask(key)
+		f.masks[key] = mask
+	}
+
+	floatFrame := gocv.NewMat()
+	defer floatFrame.Close()
+	frame.ConvertTo(&floatFrame, gocv.MatTypeCV32FC3)
+	gocv.Multiply(floatFrame, mask, &floatFrame)
+	floatFrame.ConvertTo(frame, gocv.MatTypeCV8UC3)
+}
+
+// newVignetteMask is 1 inside radius times half the frame diagonal and darkens from there towards the corners
+func newVignetteMask(key vignetteKey) gocv.Mat {
+	centerX, centerY := float64(key.size.X)/2, float64(key.size.Y)/2
+	halfDiagonal := math.Hypot(centerX, centerY)
+	data := make([]byte, key.size.X*key.size.Y*4)
+	for y := 0; y < key.size.Y; y++ {
+		for x := 0; x < key.size.X; x++ {
+			distance := math.Hypot(float64(x)-centerX, float64(y)-centerY) / halfDiagonal
+			falloff := math.Max(0, (distance-key.radius)/(1-key.radius))
+			value := float32(1 - key.amount*falloff*falloff)
+			binary.LittleEndian.PutUint32(data[(y*key.size.X+x)*4:], math.Float32bits(value))
+		}
+	}
+	mask, err := gocv.NewMatFromBytes(key.size.Y, key.size.X, gocv.MatTypeCV32F, data)
+	if err != nil {
+		return gocv.NewMatWithSizeFromScalar(gocv.NewScalar(1, 1, 1, 0), key.size.Y, key.size.X, gocv.MatTypeCV32FC3)
+	}
+	defer mask.Close()
+	mask3 := gocv.NewMat()
+	gocv.Merge([]gocv.Mat{mask, mask, mask}, &mask3)
+	return mask3
+}
+
+type sharpenFilter struct{}
+
+func (sharpenFilter) Params() []FilterParam {
+	return []FilterParam{
+		{Name: "Amount", Min: 0, Max: 300, Step: 10, Default: 100},
+		{Name: "Radius", Min: 1, Max: 10, Step: 1, Default: 2},
+	}
+}
+
+// Apply uses an unsharp mask: the difference to a blurred copy is added back to the frame
+func (sharpenFilter) Apply(frame *gocv.Mat, params map[string]float64) {
+	amount := params["Amount"] / 100
+	sigma := params["Radius"] * frameScale(frame)
+	if amount <= 0 || sigma <= 0 {
+		return
+	}
+	blurred := gocv.NewMat()
+	defer blurred.Close()
+	gocv.GaussianBlur(*frame, &blurred, image.Pt(0, 0), sigma, sigma, gocv.BorderReplicate)
+	gocv.AddWeighted(*frame, 1+amount, blurred, -amount, 0, frame)
+}
+
+type blurFilter struct{}
+
+func (blurFilter) Params() []FilterParam {
+	return []FilterParam{{Name: "Radius", Min: 1, Max: 50, Step: 1, Default: 5}}
+}
+
+func (blurFilter) Apply(frame *gocv.Mat, params map[string]float64) {
+	sigma := params["Radius"] * frameScale(frame)
+	if sigma <= 0 {
+		return
+	}
+	gocv.GaussianBlur(*frame, frame, image.Pt(0, 0), sigma, sigma, gocv.BorderReplicate)
+}
@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"gocv.io/x/gocv"
+	"image"
+	"math"
+	"sort"
+)
+
+// MaxZoom is the furthest snapshots can zoom into the camera frame
+const MaxZoom = 5.0
+
+// Framing is the region of the camera frame a snapshot shows, zoomed in by Zoom and panned by PanX and PanY. Pans are
+// fractions of how far the region can move from the centre, -1 to the left or top edge and 1 to the right or bottom.
+type Framing struct {
+	Zoom float64
+	PanX float64
+	PanY float64
+}
+
+var DefaultFraming = Framing{Zoom: 1}
+
+// Rect is the region of a width by height frame the framing shows
+func (f Framing) Rect(width int, height int) image.Rectangle {
+	factor := math.Min(math.Max(f.Zoom, 1.0), MaxZoom)
+	zoomedWidth := int(float64(width) / factor)
+	zoomedHeight := int(float64(height) / factor)
+	xOffset := panOffset(width-zoomedWidth, f.PanX)
+	yOffset := panOffset(height-zoomedHeight, f.PanY)
+	return image.Rect(xOffset, yOffset, xOffset+zoomedWidth, yOffset+zoomedHeight)
+}
+
+// panOffset is where a region that can move by slack pixels starts when panned by pan
+func panOffset(slack int, pan float64) int {
+	pan = math.Min(math.Max(pan, -1), 1)
+	return int(math.Round(float64(slack) * (1 + pan) / 2))
+}
+
+// panFor is the pan that centres a region zoomed by zoom on centre, as near as the frame edges allow
+func panFor(centre int, size int, zoom float64) float64 {
+	zoomedSize := float64(int(float64(size) / zoom))
+	slack := float64(size) - zoomedSize
+	if slack <= 0 {
+		return 0
+	}
+	offset := math.Min(math.Max(float64(centre)-zoomedSize/2, 0), slack)
+	return 2*offset/slack - 1
+}
+
+// FramingCentredOn returns the framing zoomed by zoom that is centred on centre of a width by height frame, as near as
+// the frame edges allow
+func FramingCentredOn(centre image.Point, zoom float64, width int, height int) Framing {
+	zoom = math.Min(math.Max(zoom, 1), MaxZoom)
+	return Framing{Zoom: zoom, PanX: panFor(centre.X, width, zoom), PanY: panFor(centre.Y, height, zoom)}
+}
+
+// FramingFromRect returns the framing that shows rect of a width by height frame, as closely as the frame's aspect
+// ratio and the zoom limits allow
+func FramingFromRect(rect image.Rectangle, width int, height int) Framing {
+	zoom := math.Min(float64(width)/float64(rect.Dx()), float64(height)/float64(rect.Dy()))
+	return FramingCentredOn(rect.Min.Add(rect.Max).Div(2), zoom, width, height)
+}
+
+// Zoom scales the region rect of frame up to the frame's size
+func Zoom(frame gocv.Mat, rect image.Rectangle, dst *gocv.Mat) {
+	width, height := frame.Cols(), frame.Rows()
+	if rect == image.Rect(0, 0, width, height) {
+		frame.CopyTo(dst)
+		return
+	}
+	region := frame.Region(rect)
+	defer region.Close()
+	gocv.Resize(region, dst, image.Pt(width, height), 0, 0, gocv.InterpolationLanczos4)
+}
+
+// FramingKeyframe fixes the framing of an animation frame in a camera move
+type FramingKeyframe struct {
+	Frame   int
+	Framing Framing
+}
+
+// CameraMoveSettings keyframe the framing over the animation. Exports move smoothly from keyframe to keyframe.
+type CameraMoveSettings struct {
+	Enabled bool
+	// sorted by frame, one per frame. Inserting or deleting frames moves the keyframes after them along, so each stays
+	// on the frame it was set on.
+	Keyframes []FramingKeyframe
+}
+
+// FramingAt is the framing of animation frame index, eased between the keyframes around it. Frames before the first
+// keyframe or after the last hold it. ok is false without keyframes.
+func (s CameraMoveSettings) FramingAt(index int) (framing Framing, ok bool) {
+	keyframes := s.Keyframes
+	if len(keyframes) == 0 {
+		return Framing{}, false
+	}
+	if index <= keyframes[0].Frame {
+		return keyframes[0].Framing, true
+	}
+	for idx := 1; idx < len(keyframes); idx++ {
+		previous, next := keyframes[idx-1], keyframes[idx]
+		if index > next.Frame {
+			continue
+		}
+		t := float64(index-previous.Frame) / float64(next.Frame-previous.Frame)
+		t = t * t * (3 - 2*t) // eases out of one keyframe and into the next
+		return Framing{
+			// zooming by the same factor every frame looks steady, zooming by the same amount speeds up
+			Zoom: math.Exp(interpolate(math.Log(math.Max(previous.Framing.Zoom, 1)), math.Log(math.Max(next.Framing.Zoom, 1)), t)),
+			PanX: interpolate(previous.Framing.PanX, next.Framing.PanX, t),
+			PanY: interpolate(previous.Framing.PanY, next.Framing.PanY, t),
+		}, true
+	}
+	return keyframes[len(keyframes)-1].Framing, true
+}
+
+func interpolate(from float64, to float64, t float64) float64 {
+	return from + (to-from)*t
+}
+
+// WithFramingKeyframe returns a copy of keyframes with keyframe added, replacing the one on the same frame
+func WithFramingKeyframe(keyframes []FramingKeyframe, keyframe FramingKeyframe) []FramingKeyframe {
+	updated := append(WithoutFramingKeyframe(keyframes, keyframe.Frame), keyframe)
+	sort.Slice(updated, func(i, j int) bool {
+		return updated[i].Frame < updated[j].Frame
+	})
+	return updated
+}
+
+// WithoutFramingKeyframe returns a copy of keyframes without the one on frame
+func WithoutFramingKeyframe(keyframes []FramingKeyframe, frame int) []FramingKeyframe {
+	updated := make([]FramingKeyframe, 0, len(keyframes)+1)
+	for _, keyframe := range keyframes {
+		if keyframe.Frame != frame {
+			updated = append(updated, keyframe)
+		}
+	}
+	return updated
+}
+
+// frameInserted moves the keyframes on index and after it along by one, after a frame is inserted at index
+func (s *CameraMoveSettings) frameInserted(index int) {
+	updated := make([]FramingKeyframe, 0, len(s.Keyframes))
+	for _, keyframe := range s.Keyframes {
+		if keyframe.Frame >= index {
+			keyframe.Frame++
+		}
+		updated = append(updated, keyframe)
+	}
+	s.Keyframes = updated
+}
+
+// frameRemoved drops the keyframe on index and moves the ones after it back by one, after the frame at index is
+// removed
+func (s *CameraMoveSettings) frameRemoved(index int) {
+	updated := make([]FramingKeyframe, 0, len(s.Keyframes))
+	for _, keyframe := range s.Keyframes {
+		if keyframe.Frame == index {
+			continue
+		}
+		if keyframe.Frame > index {
+			keyframe.Frame--
+		}
+		updated = append(updated, keyframe)
+	}
+	s.Keyframes = updated
+}
@@ -0,0 +1,193 @@
+package backend
+
+import (
+	"image"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFramingRect(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing Framing
+		want    image.Rectangle
+	}{
+		{"full frame", Framing{Zoom: 1}, image.Rect(0, 0, 1000, 500)},
+		{"centred", Framing{Zoom: 2}, image.Rect(250, 125, 750, 375)},
+		{"left top edge", Framing{Zoom: 2, PanX: -1, PanY: -1}, image.Rect(0, 0, 500, 250)},
+		{"right bottom edge", Framing{Zoom: 2, PanX: 1, PanY: 1}, image.Rect(500, 250, 1000, 500)},
+		{"halfway", Framing{Zoom: 2, PanX: 0.5, PanY: -0.5}, image.Rect(375, 63, 875, 313)},
+		{"pan clamped", Framing{Zoom: 2, PanX: -3, PanY: 7}, image.Rect(0, 250, 500, 500)},
+		{"pan without zoom", Framing{Zoom: 1, PanX: 1, PanY: -1}, image.Rect(0, 0, 1000, 500)},
+		{"zoom below 1", Framing{Zoom: 0.5}, image.Rect(0, 0, 1000, 500)},
+		{"zoom unset", Framing{}, image.Rect(0, 0, 1000, 500)},
+		{"zoom limit", Framing{Zoom: 50, PanX: 1}, image.Rect(800, 200, 1000, 300)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rect := test.framing.Rect(1000, 500)
+			if rect != test.want {
+				t.Errorf("%v, want %v", rect, test.want)
+			}
+			if !rect.In(image.Rect(0, 0, 1000, 500)) {
+				t.Errorf("%v leaves the frame", rect)
+			}
+		})
+	}
+}
+
+func TestFramingCentredOn(t *testing.T) {
+	tests := []struct {
+		name   string
+		centre image.Point
+		zoom   float64
+		want   Framing
+	}{
+		{"centre", image.Pt(500, 250), 2, Framing{Zoom: 2}},
+		{"left top corner", image.Pt(0, 0), 2, Framing{Zoom: 2, PanX: -1, PanY: -1}},
+		{"past the right bottom corner", image.Pt(2000, 900), 2, Framing{Zoom: 2, PanX: 1, PanY: 1}},
+		{"no zoom", image.Pt(0, 0), 1, Framing{Zoom: 1}},
+		{"zoom limit", image.Pt(500, 250), 10, Framing{Zoom: MaxZoom}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			framing := FramingCentredOn(test.centre, test.zoom, 1000, 500)
+			if framing != test.want {
+				t.Errorf("%+v, want %+v", framing, test.want)
+			}
+		})
+	}
+}
+
+func TestFramingFromRect(t *testing.T) {
+	rect := image.Rect(100, 50, 600, 300)
+	framing := FramingFromRect(rect, 1000, 500)
+	if framing.Zoom != 2 {
+		t.Errorf("zoom %.2f, want 2", framing.Zoom)
+	}
+	if shown := framing.Rect(1000, 500); shown != rect {
+		t.Errorf("shows %v, want %v", shown, rect)
+	}
+	// a rect of another aspect ratio is shown whole
+	framing = FramingFromRect(image.Rect(400, 0, 600, 400), 1000, 500)
+	if shown := framing.Rect(1000, 500); !image.Rect(400, 0, 600, 400).In(shown.Inset(-1)) {
+		t.Errorf("shows %v, which cuts off the rect", shown)
+	}
+}
+
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestFramingAt(t *testing.T) {
+	move := CameraMoveSettings{Keyframes: []FramingKeyframe{
+		{Frame: 10, Framing: Framing{Zoom: 1, PanX: -1, PanY: 0}},
+		{Frame: 20, Framing: Framing{Zoom: 4, PanX: 1, PanY: 0.5}},
+	}}
+	tests := []struct {
+		name  string
+		index int
+		want  Framing
+	}{
+		{"before the first keyframe", 0, Framing{Zoom: 1, PanX: -1}},
+		{"on the first keyframe", 10, Framing{Zoom: 1, PanX: -1}},
+		// smoothstep(0.2) = 0.104, zoom moves by the same factor each frame
+		{"eased in", 12, Framing{Zoom: math.Pow(4, 0.104), PanX: -1 + 2*0.104, PanY: 0.5 * 0.104}},
+		{"halfway", 15, Framing{Zoom: 2, PanX: 0, PanY: 0.25}},
+		{"eased out", 18, Framing{Zoom: math.Pow(4, 0.896), PanX: -1 + 2*0.896, PanY: 0.5 * 0.896}},
+		{"on the last keyframe", 20, Framing{Zoom: 4, PanX: 1, PanY: 0.5}},
+		{"after the last keyframe", 100, Framing{Zoom: 4, PanX: 1, PanY: 0.5}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			framing, ok := move.FramingAt(test.index)
+			if !ok {
+				t.Fatal("no framing")
+			}
+			if !near(framing.Zoom, test.want.Zoom) || !near(framing.PanX, test.want.PanX) || !near(framing.PanY, test.want.PanY) {
+				t.Errorf("%+v, want %+v", framing, test.want)
+			}
+		})
+	}
+
+	if _, ok := (CameraMoveSettings{}).FramingAt(0); ok {
+		t.Error("framing without keyframes")
+	}
+}
+
+func TestFramingAtEasesWithoutOvershoot(t *testing.T) {
+	move := CameraMoveSettings{Keyframes: []FramingKeyframe{
+		{Frame: 0, Framing: Framing{Zoom: 1, PanX: -1}},
+		{Frame: 10, Framing: Framing{Zoom: 3, PanX: 1}},
+	}}
+	previous, _ := move.FramingAt(0)
+	for index := 1; index <= 10; index++ {
+		framing, _ := move.FramingAt(index)
+		if framing.Zoom < previous.Zoom || framing.PanX < previous.PanX {
+			t.Errorf("frame %d moves back: %+v after %+v", index, framing, previous)
+		}
+		if framing.Zoom > 3 || framing.PanX > 1 {
+			t.Errorf("frame %d overshoots: %+v", index, framing)
+		}
+		previous = framing
+	}
+}
+
+func TestWithFramingKeyframe(t *testing.T) {
+	keyframes := WithFramingKeyframe(nil, FramingKeyframe{Frame: 5, Framing: Framing{Zoom: 2}})
+	keyframes = WithFramingKeyframe(keyframes, FramingKeyframe{Frame: 1, Framing: Framing{Zoom: 1}})
+	keyframes = WithFramingKeyframe(keyframes, FramingKeyframe{Frame: 5, Framing: Framing{Zoom: 3}})
+	want := []FramingKeyframe{{Frame: 1, Framing: Framing{Zoom: 1}}, {Frame: 5, Framing: Framing{Zoom: 3}}}
+	if !reflect.DeepEqual(keyframes, want) {
+		t.Errorf("%+v, want %+v", keyframes, want)
+	}
+	keyframes = WithoutFramingKeyframe(keyframes, 1)
+	if !reflect.DeepEqual(keyframes, want[1:]) {
+		t.Errorf("%+v, want %+v", keyframes, want[1:])
+	}
+}
+
+func keyframesOn(frames ...int) []FramingKeyframe {
+	keyframes := make([]FramingKeyframe, 0)
+	for _, frame := range frames {
+		keyframes = append(keyframes, FramingKeyframe{Frame: frame, Framing: Framing{Zoom: float64(frame + 1)}})
+	}
+	return keyframes
+}
+
+func TestCameraMoveFollowsInsertedAndRemovedFrames(t *testing.T) {
+	move := CameraMoveSettings{Keyframes: keyframesOn(2, 5, 8)}
+	move.frameInserted(5)
+	want := []FramingKeyframe{keyframesOn(2)[0], {Frame: 6, Framing: Framing{Zoom: 6}}, {Frame: 9, Framing: Framing{Zoom: 9}}}
+	if !reflect.DeepEqual(move.Keyframes, want) {
+		t.Errorf("after inserting: %+v, want %+v", move.Keyframes, want)
+	}
+	move.frameRemoved(6)
+	want = []FramingKeyframe{keyframesOn(2)[0], {Frame: 8, Framing: Framing{Zoom: 9}}}
+	if !reflect.DeepEqual(move.Keyframes, want) {
+		t.Errorf("after removing: %+v, want %+v", move.Keyframes, want)
+	}
+	move.frameRemoved(0)
+	want = []FramingKeyframe{{Frame: 1, Framing: Framing{Zoom: 3}}, {Frame: 7, Framing: Framing{Zoom: 9}}}
+	if !reflect.DeepEqual(move.Keyframes, want) {
+		t.Errorf("after removing the first frame: %+v, want %+v", move.Keyframes, want)
+	}
+}
+
+func TestInsertAndRemoveMoveKeyframes(t *testing.T) {
+	animation := AnimationBackend{Frames: []*Frame{{}, {}, {}}}
+	animation.Settings.CameraMove.Keyframes = keyframesOn(0, 2)
+	animation.InsertAt(1, &Frame{})
+	if got := animation.Settings.CameraMove.Keyframes; got[0].Frame != 0 || got[1].Frame != 3 {
+		t.Errorf("after inserting: %+v", got)
+	}
+	animation.Append(&Frame{})
+	if got := animation.Settings.CameraMove.Keyframes; got[1].Frame != 3 {
+		t.Errorf("after appending: %+v", got)
+	}
+	animation.RemoveAt(0)
+	if got := animation.Settings.CameraMove.Keyframes; len(got) != 1 || got[0].Frame != 2 {
+		t.Errorf("after removing: %+v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+)
+
+// GarbageMatteMode is what a garbage matte forces the key to inside its polygon
+type GarbageMatteMode int
+
+const (
+	GarbageMatteBackground GarbageMatteMode = iota
+	GarbageMatteForeground
+)
+
+var GarbageMatteModes = []GarbageMatteMode{GarbageMatteBackground, GarbageMatteForeground}
+
+func (m GarbageMatteMode) String() string {
+	if m == GarbageMatteForeground {
+		return "Always Foreground"
+	}
+	return "Always Background"
+}
+
+// FramePoint is a position on the captured frame as fractions of its width and height, so it survives resolution changes
+type FramePoint struct {
+	X float64
+	Y float64
+}
+
+// ToImage maps p to a pixel of an image of the given size that shows region of a frameSize frame
+func (p FramePoint) ToImage(frameSize image.Point, region image.Rectangle, size image.Point) image.Point {
+	x := (p.X*float64(frameSize.X) - float64(region.Min.X)) * float64(size.X) / float64(region.Dx())
+	y := (p.Y*float64(frameSize.Y) - float64(region.Min.Y)) * float64(size.Y) / float64(region.Dy())
+	return image.Pt(int(x+0.5), int(y+0.5))
+}
+
+// FramePointFromImage maps a pixel of an image of the given size that shows region of a frameSize frame back to the frame
+func FramePointFromImage(pt image.Point, frameSize image.Point, region image.Rectangle, size image.Point) FramePoint {
+	x := float64(region.Min.X) + float64(pt.X)*float64(region.Dx())/float64(size.X)
+	y := float64(region.Min.Y) + float64(pt.Y)*float64(region.Dy())/float64(size.Y)
+	return FramePoint{X: x / float64(frameSize.X), Y: y / float64(frameSize.Y)}
+}
+
+// GarbageMatte is a polygon over the captured frame that is always keyed out or always kept, whatever the key makes
+// of it. It covers rigs, stands and the edges of the screen.
+type GarbageMatte struct {
+	Enabled bool
+	Mode    GarbageMatteMode
+	Points  []FramePoint
+}
+
+// Clone returns a copy of the matte that doesn't share its points
+func (g GarbageMatte) Clone() GarbageMatte {
+	g.Points = append([]FramePoint{}, g.Points...)
+	return g
+}
+
+// ImagePoints returns the matte's vertices on an image of the given size that shows region of a frameSize frame
+func (g GarbageMatte) ImagePoints(frameSize image.Point, region image.Rectangle, size image.Point) []image.Point {
+	points := make([]image.Point, 0, len(g.Points))
+	for _, point := range g.Points {
+		points = append(points, point.ToImage(frameSize, region, size))
+	}
+	return points
+}
+
+// GarbageMask is the project's garbage mattes for a key matte that shows Region of a FrameSize captured frame
+type GarbageMask struct {
+	Mattes    []GarbageMatte
+	Region    image.Rectangle
+	FrameSize image.Point
+}
+
+// Apply forces the inside of the garbage mattes to background or foreground in an 8 bit single channel key matte.
+// Foreground mattes are applied last, so they win where the two overlap.
+func (g GarbageMask) Apply(matte *gocv.Mat) {
+	if g.Region.Empty() {
+		return
+	}
+	size := image.Pt(matte.Cols(), matte.Rows())
+	for _, mode := range GarbageMatteModes {
+		value := color.RGBA{}
+		if mode == GarbageMatteForeground {
+			value = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		}
+		for _, garbage := range g.Mattes {
+			if !garbage.Enabled || garbage.Mode != mode || len(garbage.Points) < 3 {
+				continue
+			}
+			gocv.FillPoly(matte, [][]image.Point{garbage.ImagePoints(g.FrameSize, g.Region, size)}, value)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package backend
+
+import (
+	"gocv.io/x/gocv"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// consecutive failed reads before a camera is considered disconnected
+	maxFailedReads = 5
+
+	// how long the grabber waits before reading again when there is no camera or a read failed
+	grabberIdleSleep = 100 * time.Millisecond
+)
+
+// frameGrabber reads the current camera on a dedicated goroutine and keeps the newest frame, conformed to
+// the project resolution. The live view and snapshots both take their frames from it, so snapshots are the
+// exact full resolution frame rather than whatever the preview last showed.
+type frameGrabber struct {
+	lock sync.Mutex
+	// newest frame, swapped with the grabber's read buffer so frames are never copied while the lock is held
+	frame     gocv.Mat
+	deviceID  int
+	sequence  uint64
+	frameTime time.Time
+	// closed and replaced whenever a new frame arrives
+	frameReady chan struct{}
+
+	failedReads int
+	running     bool
+	stopped     chan struct{}
+}
+
+var grabber = frameGrabber{
+	frame:      gocv.NewMat(),
+	deviceID:   -1,
+	frameReady: make(chan struct{}),
+}
+
+// StartCapture starts reading the current camera in the background
+func StartCapture() {
+	grabber.lock.Lock()
+	defer grabber.lock.Unlock()
+	if grabber.running {
+		return
+	}
+	grabber.running = true
+	grabber.stopped = make(chan struct{})
+	go grabber.run()
+}
+
+// StopCapture stops the background reads and waits for the one in progress, so cameras can be closed safely
+func StopCapture() {
+	grabber.lock.Lock()
+	if !grabber.running {
+		grabber.lock.Unlock()
+		return
+	}
+	grabber.running = false
+	stopped := grabber.stopped
+	grabber.lock.Unlock()
+	<-stopped
+}
+
+func (g *frameGrabber) isRunning() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.running
+}
+
+func (g *frameGrabber) run() {
+	defer close(g.stopped)
+	reading := gocv.NewMat()
+	defer reading.Close()
+
+	for g.isRunning() {
+		deviceID := CurrentWebcamID
+		if deviceID < 0 || IsReconnecting(deviceID) {
+			time.Sleep(grabberIdleSleep)
+			continue
+		}
+		if !ReadCamera(deviceID, &reading) || reading.Empty() {
+			g.readFailed(deviceID)
+			time.Sleep(grabberIdleSleep)
+			continue
+		}
+		ConformToResolution(&reading, CurrentResolution())
+
+		g.lock.Lock()
+		g.frame, reading = reading, g.frame
+		g.deviceID = deviceID
+		g.sequence++
+		g.frameTime = time.Now()
+		g.failedReads = 0
+		close(g.frameReady)
+		g.frameReady = make(chan struct{})
+		g.lock.Unlock()
+	}
+}
+
+// readFailed treats a run of failed reads as a lost device and starts reopening it in the background
+func (g *frameGrabber) readFailed(deviceID int) {
+	g.lock.Lock()
+	g.failedReads++
+	failedReads := g.failedReads
+	g.lock.Unlock()
+	if failedReads < maxFailedReads {
+		log.Printf("Device closed or empty read from webcam")
+		return
+	}
+	ReconnectCamera(deviceID, func() {
+		log.Printf("cam %d is back", deviceID)
+	})
+}
+
+// NextFrame waits up to timeout for a frame of the current camera newer than sequence, copies it into dst
+// and returns its sequence number
+func NextFrame(sequence uint64, dst *gocv.Mat, timeout time.Duration) (uint64, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		grabber.lock.Lock()
+		if grabber.sequence > sequence && grabber.deviceID == CurrentWebcamID && !grabber.frame.Empty() {
+			grabber.frame.CopyTo(dst)
+			sequence = grabber.sequence
+			grabber.lock.Unlock()
+			return sequence, true
+		}
+		frameReady := grabber.frameReady
+		grabber.lock.Unlock()
+
+		select {
+		case <-frameReady:
+		case <-deadline.C:
+			return sequence, false
+		}
+	}
+}
+
+// FrameSequence returns the sequence number of the newest frame, to wait for the ones after it with NextFrame
+func FrameSequence() uint64 {
+	grabber.lock.Lock()
+	defer grabber.lock.Unlock()
+	return grabber.sequence
+}
+
+// LastFrameTime returns when the newest frame was read
+func LastFrameTime() time.Time {
+	grabber.lock.Lock()
+	defer grabber.lock.Unlock()
+	return grabber.frameTime
+}
+
+// IsCameraLost reports whether the current camera stopped delivering frames
+func IsCameraLost() bool {
+	grabber.lock.Lock()
+	failedReads := grabber.failedReads
+	grabber.lock.Unlock()
+	return failedReads >= maxFailedReads || IsReconnecting(CurrentWebcamID)
+}
@@ -0,0 +1,193 @@
+package backend
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TimelapseSettings is the schedule of a time-lapse capture session
+type TimelapseSettings struct {
+	IntervalSeconds   int
+	StartDelaySeconds int
+	// number of snapshots to take, 0 keeps going until EndTime or until stopped
+	Count int
+	// local time of day in "15:04" format after which no more snapshots are taken, empty for none
+	EndTime string
+}
+
+const (
+	defaultTimelapseInterval = 10
+	TimelapseEndTimeFormat   = "15:04"
+)
+
+// NextEndTime returns the first occurrence of the configured end time after now
+func (s TimelapseSettings) NextEndTime(now time.Time) (time.Time, error) {
+	if s.EndTime == "" {
+		return time.Time{}, nil
+	}
+	clock, err := time.ParseInLocation(TimelapseEndTimeFormat, s.EndTime, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("end time %q is not in HH:MM format", s.EndTime)
+	}
+	endTime := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !endTime.After(now) {
+		endTime = endTime.AddDate(0, 0, 1)
+	}
+	return endTime, nil
+}
+
+// Intervalometer schedules time-lapse snapshots. It is polled by the capture loop rather than running
+// its own timer, so it only fires when a fresh frame can actually be taken. Shots are scheduled on the
+// wall clock: if the loop stalls for several intervals they are counted as missed instead of being
+// taken in a burst once it recovers.
+type Intervalometer struct {
+	lock sync.Mutex
+
+	interval time.Duration
+	count    int
+	endTime  time.Time
+
+	running  bool
+	paused   bool
+	nextShot time.Time
+	// time left until the next shot when paused
+	remaining time.Duration
+
+	taken  int
+	missed int
+}
+
+func (i *Intervalometer) Start(settings TimelapseSettings, now time.Time) error {
+	if settings.IntervalSeconds < 1 {
+		return fmt.Errorf("interval must be at least 1 second")
+	}
+	if settings.Count < 0 || settings.StartDelaySeconds < 0 {
+		return fmt.Errorf("frame count and start delay can't be negative")
+	}
+	endTime, err := settings.NextEndTime(now)
+	if err != nil {
+		return err
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.interval = time.Duration(settings.IntervalSeconds) * time.Second
+	i.count = settings.Count
+	i.endTime = endTime
+	i.running = true
+	i.paused = false
+	i.nextShot = now.Add(time.Duration(settings.StartDelaySeconds) * time.Second)
+	i.taken = 0
+	i.missed = 0
+	return nil
+}
+
+func (i *Intervalometer) Stop() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.running = false
+	i.paused = false
+}
+
+func (i *Intervalometer) Pause(now time.Time) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if !i.running || i.paused {
+		return
+	}
+	i.paused = true
+	i.remaining = i.nextShot.Sub(now)
+	if i.remaining < 0 {
+		i.remaining = 0
+	}
+}
+
+// Resume continues a paused session, keeping the time that was left until the next shot
+func (i *Intervalometer) Resume(now time.Time) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if !i.running || !i.paused {
+		return
+	}
+	i.paused = false
+	i.nextShot = now.Add(i.remaining)
+}
+
+func (i *Intervalometer) IsRunning() bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.running
+}
+
+func (i *Intervalometer) IsPaused() bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.paused
+}
+
+// Due reports whether a snapshot should be taken now and schedules the one after it.
+// Every shot that was due must be reported back with Shot or Miss.
+func (i *Intervalometer) Due(now time.Time) bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if !i.running || i.paused || now.Before(i.nextShot) {
+		return false
+	}
+	i.nextShot = i.nextShot.Add(i.interval)
+	for !i.nextShot.After(now) { // slots that passed while the capture loop was stalled
+		i.nextShot = i.nextShot.Add(i.interval)
+		i.missed++
+	}
+	return true
+}
+
+func (i *Intervalometer) Shot() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.taken++
+}
+
+func (i *Intervalometer) Miss() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.missed++
+}
+
+// Finished reports whether the frame count has been reached or the next shot would fall after the end time
+func (i *Intervalometer) Finished() bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.count > 0 && i.taken >= i.count {
+		return true
+	}
+	return !i.endTime.IsZero() && i.nextShot.After(i.endTime)
+}
+
+// Status describes the progress of the session and counts down to the next shot
+func (i *Intervalometer) Status(now time.Time) string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if !i.running {
+		return ""
+	}
+	progress := fmt.Sprintf("%d taken", i.taken)
+	if i.count > 0 {
+		progress = fmt.Sprintf("%d/%d taken", i.taken, i.count)
+	}
+	if i.missed > 0 {
+		progress = fmt.Sprintf("%s, %d missed", progress, i.missed)
+	}
+	if i.paused {
+		return fmt.Sprintf("Time-lapse paused: %s, next in %s", progress, formatCountdown(i.remaining))
+	}
+	return fmt.Sprintf("Time-lapse: %s, next in %s", progress, formatCountdown(i.nextShot.Sub(now)))
+}
+
+func formatCountdown(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	seconds := int((d + time.Second - 1) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
@@ -0,0 +1,183 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+var intervalometerStart = time.Date(2020, 6, 1, 12, 0, 0, 0, time.Local)
+
+func startIntervalometer(t *testing.T, settings TimelapseSettings) *Intervalometer {
+	intervalometer := Intervalometer{}
+	err := intervalometer.Start(settings, intervalometerStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &intervalometer
+}
+
+func at(seconds float64) time.Time {
+	return intervalometerStart.Add(time.Duration(seconds * float64(time.Second)))
+}
+
+func TestIntervalometerStartErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings TimelapseSettings
+	}{
+		{"no interval", TimelapseSettings{}},
+		{"negative count", TimelapseSettings{IntervalSeconds: 1, Count: -1}},
+		{"negative delay", TimelapseSettings{IntervalSeconds: 1, StartDelaySeconds: -1}},
+		{"bad end time", TimelapseSettings{IntervalSeconds: 1, EndTime: "25:00"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			intervalometer := Intervalometer{}
+			if intervalometer.Start(test.settings, intervalometerStart) == nil {
+				t.Error("expected an error")
+			}
+			if intervalometer.IsRunning() {
+				t.Error("running after a failed start")
+			}
+		})
+	}
+}
+
+func TestIntervalometerDue(t *testing.T) {
+	intervalometer := startIntervalometer(t, TimelapseSettings{IntervalSeconds: 10, StartDelaySeconds: 5})
+	tests := []struct {
+		seconds float64
+		due     bool
+	}{
+		{0, false},
+		{4.9, false},
+		{5, true},
+		{5.1, false},
+		{14.9, false},
+		{15, true},
+		{25.5, true},
+		{30, false},
+	}
+	for _, test := range tests {
+		if due := intervalometer.Due(at(test.seconds)); due != test.due {
+			t.Errorf("Due at %.1fs = %t, want %t", test.seconds, due, test.due)
+		}
+	}
+}
+
+func TestIntervalometerMissesStalledSlots(t *testing.T) {
+	intervalometer := startIntervalometer(t, TimelapseSettings{IntervalSeconds: 10})
+	if !intervalometer.Due(at(0)) {
+		t.Fatal("first shot not due")
+	}
+	intervalometer.Shot()
+	// the loop stalls through the shots at 10, 20 and 30 seconds, only one is taken late
+	if !intervalometer.Due(at(35)) {
+		t.Fatal("shot not due after the stall")
+	}
+	if intervalometer.Due(at(39)) {
+		t.Error("stalled shots taken in a burst")
+	}
+	if !intervalometer.Due(at(40)) {
+		t.Error("schedule moved by the stall")
+	}
+	if intervalometer.missed != 2 {
+		t.Errorf("%d missed, want 2", intervalometer.missed)
+	}
+}
+
+func TestIntervalometerPauseResume(t *testing.T) {
+	intervalometer := startIntervalometer(t, TimelapseSettings{IntervalSeconds: 10})
+	intervalometer.Due(at(0))
+	intervalometer.Pause(at(4))
+	if !intervalometer.IsPaused() {
+		t.Fatal("not paused")
+	}
+	if intervalometer.Due(at(60)) {
+		t.Error("due while paused")
+	}
+	intervalometer.Pause(at(70)) // pausing again keeps the time left from the first pause
+	intervalometer.Resume(at(100))
+	if intervalometer.IsPaused() {
+		t.Fatal("still paused")
+	}
+	if intervalometer.Due(at(105.9)) {
+		t.Error("due before the 6 seconds left at the pause passed")
+	}
+	if !intervalometer.Due(at(106)) {
+		t.Error("not due 6 seconds after resuming")
+	}
+	if intervalometer.missed != 0 {
+		t.Errorf("%d missed while paused, want 0", intervalometer.missed)
+	}
+}
+
+func TestIntervalometerResumeWithoutPause(t *testing.T) {
+	intervalometer := startIntervalometer(t, TimelapseSettings{IntervalSeconds: 10})
+	intervalometer.Due(at(0))
+	intervalometer.Resume(at(50))
+	if !intervalometer.Due(at(50)) {
+		t.Error("resuming a running session moved the schedule")
+	}
+}
+
+func TestIntervalometerFinishesAfterCount(t *testing.T) {
+	intervalometer := startIntervalometer(t, TimelapseSettings{IntervalSeconds: 1, Count: 2})
+	for shot := 0; shot < 2; shot++ {
+		if intervalometer.Finished() {
+			t.Fatalf("finished after %d of 2 shots", shot)
+		}
+		intervalometer.Due(at(float64(shot)))
+		intervalometer.Shot()
+	}
+	if !intervalometer.Finished() {
+		t.Error("not finished after 2 of 2 shots")
+	}
+}
+
+func TestIntervalometerMissesDontCount(t *testing.T) {
+	intervalometer := startIntervalometer(t, TimelapseSettings{IntervalSeconds: 1, Count: 1})
+	intervalometer.Due(at(0))
+	intervalometer.Miss()
+	if intervalometer.Finished() {
+		t.Error("a missed shot counted towards the frame count")
+	}
+}
+
+func TestIntervalometerFinishesAtEndTime(t *testing.T) {
+	intervalometer := startIntervalometer(t, TimelapseSettings{IntervalSeconds: 60, EndTime: "12:02"})
+	for minute := 0; minute < 2; minute++ {
+		intervalometer.Due(at(float64(minute * 60)))
+		intervalometer.Shot()
+		if intervalometer.Finished() {
+			t.Fatalf("finished before the shot at 12:%02d", minute+1)
+		}
+	}
+	intervalometer.Due(at(120))
+	intervalometer.Shot()
+	if !intervalometer.Finished() {
+		t.Error("not finished once the next shot falls after the end time")
+	}
+}
+
+func TestNextEndTime(t *testing.T) {
+	tests := []struct {
+		endTime string
+		want    time.Time
+	}{
+		{"", time.Time{}},
+		{"13:30", time.Date(2020, 6, 1, 13, 30, 0, 0, time.Local)},
+		{"12:00", time.Date(2020, 6, 2, 12, 0, 0, 0, time.Local)},
+		{"09:15", time.Date(2020, 6, 2, 9, 15, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		endTime, err := TimelapseSettings{EndTime: test.endTime}.NextEndTime(intervalometerStart)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.endTime, err.Error())
+			continue
+		}
+		if !endTime.Equal(test.want) {
+			t.Errorf("%q: end time %s, want %s", test.endTime, endTime, test.want)
+		}
+	}
+}
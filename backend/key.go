@@ -0,0 +1,302 @@
+package backend
+
+import (
+	"fmt"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+	"math"
+
+	"../util"
+)
+
+// KeySettings control how the chroma key separates the subject from the screen behind it
+type KeySettings struct {
+	Color color.RGBA
+	// how far hue, saturation and value may be from those of Color and still be keyed, in OpenCV HSV units
+	// (hue 0..179, saturation and value 0..255)
+	HueTolerance        float64
+	SaturationTolerance float64
+	ValueTolerance      float64
+	// feathers the matte edge, in pixels of the captured frame
+	Softness float64
+	// shrinks the subject by this many pixels of the captured frame, negative values grow it
+	Choke float64
+	// 0..1, how much of the screen colour reflected onto the subject is removed
+	SpillSuppression float64
+}
+
+var DefaultKeySettings = KeySettings{
+	Color:               color.RGBA{G: 255, A: 255},
+	HueTolerance:        20,
+	SaturationTolerance: 150,
+	ValueTolerance:      150,
+	Softness:            2,
+	SpillSuppression:    0.5,
+}
+
+// HSV returns the hue, saturation and value of c the way OpenCV stores them in 8 bit images
+func HSV(c color.RGBA) (float64, float64, float64) {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	if max == 0 {
+		return 0, 0, 0
+	}
+	saturation := (max - min) / max * 255
+	if max == min {
+		return 0, saturation, max
+	}
+	var hue float64
+	switch max {
+	case r:
+		hue = 60 * (g - b) / (max - min)
+	case g:
+		hue = 120 + 60*(b-r)/(max-min)
+	default:
+		hue = 240 + 60*(r-g)/(max-min)
+	}
+	if hue < 0 {
+		hue += 360
+	}
+	return hue / 2, saturation, max
+}
+
+// keyHueRanges returns the hue ranges within tolerance of hue, split in two where they wrap around red
+func keyHueRanges(hue float64, tolerance float64) [][2]float64 {
+	if tolerance >= 90 {
+		return [][2]float64{{0, 179}}
+	}
+	low, high := hue-tolerance, hue+tolerance
+	if low < 0 {
+		return [][2]float64{{0, high}, {low + 180, 179}}
+	}
+	if high > 179 {
+		return [][2]float64{{low, 179}, {0, high - 180}}
+	}
+	return [][2]float64{{low, high}}
+}
+
+// KeyMatte computes the subject matte of a BGR frame: 255 where the subject is, 0 where the screen is, with soft edges
+func KeyMatte(frame gocv.Mat, settings KeySettings, matte *gocv.Mat) {
+	hsv := gocv.NewMat()
+	defer hsv.Close()
+	gocv.CvtColor(frame, &hsv, gocv.ColorBGRToHSV)
+
+	hue, saturation, value := HSV(settings.Color)
+	screen := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), frame.Rows(), frame.Cols(), gocv.MatTypeCV8UC1)
+	defer screen.Close()
+	for _, hueRange := range keyHueRanges(hue, settings.HueTolerance) {
+		lower := gocv.NewScalar(hueRange[0], math.Max(saturation-settings.SaturationTolerance, 0), math.Max(value-settings.ValueTolerance, 0), 0)
+		upper := gocv.NewScalar(hueRange[1], math.Min(saturation+settings.SaturationTolerance, 255), math.Min(value+settings.ValueTolerance, 255), 0)
+		inRange := gocv.NewMat()
+		gocv.InRangeWithScalar(hsv, lower, upper, &inRange)
+		gocv.BitwiseOr(screen, inRange, &screen)
+		inRange.Close()
+	}
+	gocv.BitwiseNot(screen, matte)
+	scale := frameScale(&frame)
+	chokeMatte(matte, settings.Choke*scale)
+	featherMatte(matte, settings.Softness*scale)
+}
+
+// chokeMatte shrinks the subject of a matte by choke pixels, negative values grow it
+func chokeMatte(matte *gocv.Mat, choke float64) {
+	radius := int(math.Round(math.Abs(choke)))
+	if radius == 0 {
+		return
+	}
+	kernel := gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(2*radius+1, 2*radius+1))
+	defer kernel.Close()
+	if choke > 0 {
+		gocv.Erode(*matte, matte, kernel)
+	} else {
+		gocv.Dilate(*matte, matte, kernel)
+	}
+}
+
+// cleanMatte removes specks from a matte and fills holes in it that are smaller than radius pixels
+func cleanMatte(matte *gocv.Mat, radius float64) {
+	size := int(math.Round(radius))
+	if size == 0 {
+		return
+	}
+	kernel := gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(2*size+1, 2*size+1))
+	defer kernel.Close()
+	gocv.MorphologyEx(*matte, matte, gocv.MorphOpen, kernel)
+	gocv.MorphologyEx(*matte, matte, gocv.MorphClose, kernel)
+}
+
+// featherMatte blurs the edges of a matte over softness pixels
+func featherMatte(matte *gocv.Mat, softness float64) {
+	if softness > 0 {
+		gocv.GaussianBlur(*matte, matte, image.Pt(0, 0), softness, softness, gocv.BorderReplicate)
+	}
+}
+
+// SuppressSpill pulls the screen colour reflected onto the subject back towards neutral. Green screens limit green to
+// the brighter of red and blue, blue screens limit blue to the brighter of red and green.
+func SuppressSpill(frame *gocv.Mat, settings KeySettings) {
+	if settings.SpillSuppression <= 0 {
+		return
+	}
+	channels := gocv.Split(*frame)
+	defer func() {
+		for _, channel := range channels {
+			channel.Close()
+		}
+	}()
+	spill, other1, other2 := 1, 0, 2 // BGR
+	if settings.Color.B > settings.Color.G {
+		spill, other1, other2 = 0, 1, 2
+	}
+	limit := gocv.NewMat()
+	defer limit.Close()
+	gocv.Max(channels[other1], channels[other2], &limit)
+	excess := gocv.NewMat()
+	defer excess.Close()
+	gocv.Subtract(channels[spill], limit, &excess)
+	excess.MultiplyFloat(float32(math.Min(settings.SpillSuppression, 1)))
+	gocv.Subtract(channels[spill], excess, &channels[spill])
+	gocv.Merge(channels, frame)
+}
+
+// Composite blends foreground over background, both BGR, weighted by an 8 bit single channel matte
+func Composite(foreground gocv.Mat, background gocv.Mat, matte gocv.Mat, dst *gocv.Mat) {
+	alpha := gocv.NewMat()
+	defer alpha.Close()
+	matte.ConvertTo(&alpha, gocv.MatTypeCV32F)
+	alpha.MultiplyFloat(1.0 / 255)
+	alpha3 := gocv.NewMat()
+	defer alpha3.Close()
+	gocv.Merge([]gocv.Mat{alpha, alpha, alpha}, &alpha3)
+	inverseAlpha3 := gocv.NewMat()
+	defer inverseAlpha3.Close()
+	gocv.AddWeighted(alpha3, -1, alpha3, 0, 1, &inverseAlpha3)
+
+	foregroundFloat := gocv.NewMat()
+	defer foregroundFloat.Close()
+	foreground.ConvertTo(&foregroundFloat, gocv.MatTypeCV32FC3)
+	gocv.Multiply(foregroundFloat, alpha3, &foregroundFloat)
+	backgroundFloat := gocv.NewMat()
+	defer backgroundFloat.Close()
+	background.ConvertTo(&backgroundFloat, gocv.MatTypeCV32FC3)
+	gocv.Multiply(backgroundFloat, inverseAlpha3, &backgroundFloat)
+	gocv.Add(foregroundFloat, backgroundFloat, &foregroundFloat)
+	foregroundFloat.ConvertTo(dst, gocv.MatTypeCV8UC3)
+}
+
+// ApplyKey replaces the screen in a BGR frame with background, which has the same size. With matteView the matte
+// is shown instead, white for the subject and black for the screen.
+func ApplyKey(frame gocv.Mat, background gocv.Mat, settings KeySettings, garbage GarbageMask, matteView bool, dst *gocv.Mat) {
+	matte := gocv.NewMat()
+	defer matte.Close()
+	KeyMatte(frame, settings, &matte)
+	garbage.Apply(&matte)
+	if matteView {
+		gocv.CvtColor(matte, dst, gocv.ColorGrayToBGR)
+		return
+	}
+	foreground := frame.Clone()
+	defer foreground.Close()
+	SuppressSpill(&foreground, settings)
+	Composite(foreground, background, matte, dst)
+}
+
+// DifferenceKeySettings separate the subject from a clean plate, a shot of the empty set
+type DifferenceKeySettings struct {
+	// pixels whose most different channel is within this many levels of the plate are part of the set
+	Threshold float64
+	// feathers the matte edge, in pixels of the captured frame
+	Softness float64
+	// specks and holes smaller than this many pixels of the captured frame are removed from the matte
+	Cleanup float64
+}
+
+var DefaultDifferenceKeySettings = DifferenceKeySettings{Threshold: 30, Softness: 2, Cleanup: 3}
+
+// DifferenceMatte computes the subject matte of a BGR frame from its difference to the clean plate, which has the same size
+func DifferenceMatte(frame gocv.Mat, plate gocv.Mat, settings DifferenceKeySettings, matte *gocv.Mat) {
+	difference := gocv.NewMat()
+	defer difference.Close()
+	gocv.AbsDiff(frame, plate, &difference)
+	channels := gocv.Split(difference)
+	distance := gocv.NewMat()
+	defer distance.Close()
+	gocv.Max(channels[0], channels[1], &distance)
+	gocv.Max(distance, channels[2], &distance)
+	for _, channel := range channels {
+		channel.Close()
+	}
+	gocv.Threshold(distance, matte, float32(settings.Threshold), 255, gocv.ThresholdBinary)
+	scale := frameScale(&frame)
+	cleanMatte(matte, settings.Cleanup*scale)
+	featherMatte(matte, settings.Softness*scale)
+}
+
+// ApplyDifferenceKey replaces the parts of a BGR frame that match the clean plate with background. All three have the
+// same size. With matteView the matte is shown instead.
+func ApplyDifferenceKey(frame gocv.Mat, plate gocv.Mat, background gocv.Mat, settings DifferenceKeySettings, garbage GarbageMask, matteView bool, dst *gocv.Mat) {
+	matte := gocv.NewMat()
+	defer matte.Close()
+	DifferenceMatte(frame, plate, settings, &matte)
+	garbage.Apply(&matte)
+	if matteView {
+		gocv.CvtColor(matte, dst, gocv.ColorGrayToBGR)
+		return
+	}
+	Composite(frame, background, matte, dst)
+}
+
+// LumaKeySettings separate the subject from a black or white backdrop by brightness
+type LumaKeySettings struct {
+	// pixels with a luma between Low and High, 0..255, are the backdrop
+	Low  float64
+	High float64
+	// feathers the matte edge, in pixels of the captured frame
+	Softness float64
+	// keeps the pixels between Low and High and keys out the rest instead
+	Invert bool
+}
+
+var DefaultLumaKeySettings = LumaKeySettings{Low: 0, High: 40, Softness: 2}
+
+// LumaMatte computes the subject matte of a BGR frame from its brightness
+func LumaMatte(frame gocv.Mat, settings LumaKeySettings, matte *gocv.Mat) {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(frame, &gray, gocv.ColorBGRToGray)
+	backdrop := gocv.NewMat()
+	defer backdrop.Close()
+	low, high := math.Min(settings.Low, settings.High), math.Max(settings.Low, settings.High)
+	gocv.InRangeWithScalar(gray, gocv.NewScalar(low, 0, 0, 0), gocv.NewScalar(high, 0, 0, 0), &backdrop)
+	if settings.Invert {
+		backdrop.CopyTo(matte)
+	} else {
+		gocv.BitwiseNot(backdrop, matte)
+	}
+	featherMatte(matte, settings.Softness*frameScale(&frame))
+}
+
+// ApplyLumaKey replaces the backdrop in a BGR frame with background, which has the same size. With matteView the
+// matte is shown instead.
+func ApplyLumaKey(frame gocv.Mat, background gocv.Mat, settings LumaKeySettings, garbage GarbageMask, matteView bool, dst *gocv.Mat) {
+	matte := gocv.NewMat()
+	defer matte.Close()
+	LumaMatte(frame, settings, &matte)
+	garbage.Apply(&matte)
+	if matteView {
+		gocv.CvtColor(matte, dst, gocv.ColorGrayToBGR)
+		return
+	}
+	Composite(frame, background, matte, dst)
+}
+
+// CleanPlatePath is where the project's clean plate is saved
+func CleanPlatePath(projectName string) (string, error) {
+	baseDir, err := util.GetMocapBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`%s\%s\cleanplate.png`, baseDir, projectName), nil
+}
@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"gocv.io/x/gocv"
+	"image"
+	"log"
+	"math"
+)
+
+// LayerPlacement is whether a layer is composited behind the keyed subject, like a background plate, or in front of
+// it, like a prop or a frame
+type LayerPlacement int
+
+const (
+	LayerBehind LayerPlacement = iota
+	LayerInFront
+)
+
+var LayerPlacements = []LayerPlacement{LayerBehind, LayerInFront}
+
+func (p LayerPlacement) String() string {
+	if p == LayerInFront {
+		return "In Front of Subject"
+	}
+	return "Behind Subject"
+}
+
+// LayerSettings place an image, an image sequence or a video in the layer stack composited with the capture
+type LayerSettings struct {
+	Path     string
+	Sequence bool
+	// sequences and videos start over after their last frame instead of holding it
+	Loop      bool
+	Placement LayerPlacement
+	Visible   bool
+	Opacity   float64
+	// Scale is relative to the layer fitted into the frame, offsets are fractions of the frame size
+	Scale   float64
+	OffsetX float64
+	OffsetY float64
+}
+
+// NewLayerSettings returns a visible, opaque layer filling the frame in front of the subject
+func NewLayerSettings(path string, sequence bool) LayerSettings {
+	return LayerSettings{Path: path, Sequence: sequence, Placement: LayerInFront, Visible: true, Opacity: 1, Scale: 1}
+}
+
+// LayerFrame is the frame of a layer with frameCount frames shown for an animation frame. Looping layers wrap around,
+// others hold their last frame.
+func LayerFrame(index int, frameCount int, settings LayerSettings) int {
+	if settings.Loop && frameCount > 0 {
+		return (index%frameCount + frameCount) % frameCount
+	}
+	return index
+}
+
+// LayerRect is where a layer of layerSize is placed on a frame of frameSize
+func LayerRect(layerSize image.Point, frameSize image.Point, settings LayerSettings) image.Rectangle {
+	fitWidth, fitHeight := Resolution{Width: layerSize.X, Height: layerSize.Y}.FitInto(frameSize.X, frameSize.Y)
+	scaledSize := image.Pt(int(math.Round(float64(fitWidth)*settings.Scale)), int(math.Round(float64(fitHeight)*settings.Scale)))
+	center := image.Pt(frameSize.X/2+int(math.Round(settings.OffsetX*float64(frameSize.X))),
+		frameSize.Y/2+int(math.Round(settings.OffsetY*float64(frameSize.Y))))
+	min := center.Sub(scaledSize.Div(2))
+	return image.Rectangle{Min: min, Max: min.Add(scaledSize)}
+}
+
+// BlendLayer composites a BGR or BGRA layer, already scaled to the size of at, onto the BGR frame at at. The frame
+// shows through the transparent parts of BGRA layers.
+func BlendLayer(frame *gocv.Mat, layer gocv.Mat, at image.Rectangle, opacity float64) {
+	visible := at.Intersect(image.Rect(0, 0, frame.Cols(), frame.Rows()))
+	if visible.Empty() || opacity <= 0 {
+		return
+	}
+	source := layer.Region(visible.Sub(at.Min))
+	defer source.Close()
+	target := frame.Region(visible)
+	defer target.Close()
+	if layer.Channels() < 4 {
+		gocv.AddWeighted(target, 1-opacity, source, opacity, 0, &target)
+		return
+	}
+
+	channels := gocv.Split(source)
+	defer func() {
+		for _, channel := range channels {
+			channel.Close()
+		}
+	}()
+	color := gocv.NewMat()
+	defer color.Close()
+	gocv.Merge(channels[:3], &color)
+	alpha := channels[3]
+	if opacity < 1 {
+		alpha.MultiplyFloat(float32(opacity))
+	}
+	Composite(color, target, alpha, &target)
+}
+
+// LayerStack is the opened sources of a project's layers. It keeps the current frame of each layer scaled for the
+// frame it was last composited onto, so compositing the same layers every frame doesn't rescale them.
+type LayerStack struct {
+	layers []*openLayer
+}
+
+// openLayer is the source of a layer, nil if it couldn't be opened, and its current frame
+type openLayer struct {
+	path       string
+	sequence   bool
+	source     FrameSource
+	frame      gocv.Mat
+	frameIndex int
+	scaled     gocv.Mat
+	scaledSize image.Point
+}
+
+func newOpenLayer(path string, sequence bool, source FrameSource) *openLayer {
+	return &openLayer{path: path, sequence: sequence, source: source, frame: gocv.NewMat(), frameIndex: -1, scaled: gocv.NewMat()}
+}
+
+// scaledFrame returns frame frameIndex of the layer scaled to where it is placed on a frame of frameSize
+func (l *openLayer) scaledFrame(frameIndex int, settings LayerSettings, frameSize image.Point) (gocv.Mat, image.Rectangle, bool) {
+	if frameIndex != l.frameIndex {
+		if !l.source.Frame(frameIndex, &l.frame) {
+			return gocv.Mat{}, image.Rectangle{}, false
+		}
+		l.frameIndex = frameIndex
+		l.scaledSize = image.Point{}
+	}
+	at := LayerRect(image.Pt(l.frame.Cols(), l.frame.Rows()), frameSize, settings)
+	if at.Dx() < 1 || at.Dy() < 1 {
+		return gocv.Mat{}, image.Rectangle{}, false
+	}
+	if at.Size() != l.scaledSize {
+		gocv.Resize(l.frame, &l.scaled, at.Size(), 0, 0, gocv.InterpolationLinear)
+		l.scaledSize = at.Size()
+	}
+	return l.scaled, at, true
+}
+
+func (l *openLayer) Close() {
+	if l.source != nil {
+		l.source.Close()
+	}
+	l.frame.Close()
+	l.scaled.Close()
+}
+
+// Open opens the sources of layers, keeping the ones already open and closing the ones no longer used
+func (s *LayerStack) Open(layers []LayerSettings) {
+	unused := s.layers
+	opened := make([]*openLayer, 0, len(layers))
+	for _, settings := range layers {
+		var layer *openLayer
+		for idx, candidate := range unused {
+			if candidate.path == settings.Path && candidate.sequence == settings.Sequence {
+				layer = candidate
+				unused = append(unused[:idx:idx], unused[idx+1:]...)
+				break
+			}
+		}
+		if layer == nil {
+			source, err := OpenLayerSource(settings.Path, settings.Sequence)
+			if err != nil {
+				log.Printf("error opening layer: %s", err.Error())
+				source = nil
+			}
+			layer = newOpenLayer(settings.Path, settings.Sequence, source)
+		}
+		opened = append(opened, layer)
+	}
+	for _, layer := range unused {
+		layer.Close()
+	}
+	s.layers = opened
+}
+
+// Missing reports whether the layer at idx couldn't be opened
+func (s *LayerStack) Missing(idx int) bool {
+	return idx < len(s.layers) && s.layers[idx].source == nil
+}
+
+// Apply composites the visible layers with the given placement onto dst for animation frame index. layers are the
+// settings the stack was last opened with, layers that don't match them are skipped until it is opened again.
+func (s *LayerStack) Apply(index int, layers []LayerSettings, placement LayerPlacement, dst *gocv.Mat) {
+	frameSize := image.Pt(dst.Cols(), dst.Rows())
+	for idx, settings := range layers {
+		if idx >= len(s.layers) {
+			break
+		}
+		layer := s.layers[idx]
+		if !settings.Visible || settings.Placement != placement || layer.source == nil ||
+			layer.path != settings.Path || layer.sequence != settings.Sequence {
+			continue
+		}
+		frameIndex := LayerFrame(index, layer.source.FrameCount(), settings)
+		scaled, at, ok := layer.scaledFrame(frameIndex, settings, frameSize)
+		if ok {
+			BlendLayer(dst, scaled, at, settings.Opacity)
+		}
+	}
+}
+
+func (s *LayerStack) Close() {
+	for _, layer := range s.layers {
+		layer.Close()
+	}
+	s.layers = nil
+}
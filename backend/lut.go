@@ -0,0 +1,280 @@
+package backend
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gocv.io/x/gocv"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LUTMode is where in the pipeline the project's colour grade is applied
+type LUTMode int
+
+const (
+	LUTModeCapture LUTMode = iota
+	LUTModeExport
+)
+
+var LUTModes = []LUTMode{LUTModeCapture, LUTModeExport}
+
+func (m LUTMode) String() string {
+	if m == LUTModeExport {
+		return "Export Only"
+	}
+	return "Live View and Snapshots"
+}
+
+// LUTSettings grade frames with a 3D LUT from a .cube file
+type LUTSettings struct {
+	Enabled bool
+	Path    string
+	// 0..1, how far colours are moved towards the graded ones
+	Strength float64
+	Mode     LUTMode
+}
+
+var LUTExtensions = []string{".cube"}
+
+const maxLUTSize = 256
+
+// LUT3D is a 3D colour lookup table as found in Adobe and Resolve .cube files
+type LUT3D struct {
+	Title string
+	Size  int
+	// RGB triples, red changing fastest, then green, then blue
+	Data      []float32
+	DomainMin [3]float64
+	DomainMax [3]float64
+}
+
+// LoadCubeLUT reads a 3D LUT from a .cube file
+func LoadCubeLUT(path string) (*LUT3D, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lut := LUT3D{DomainMax: [3]float64{1, 1, 1}}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "TITLE":
+			lut.Title = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "TITLE")), `"`)
+		case "LUT_1D_SIZE":
+			return nil, errors.New("1D LUTs are not supported, please use a 3D LUT")
+		case "LUT_3D_SIZE":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: invalid LUT_3D_SIZE", lineNumber)
+			}
+			lut.Size, err = strconv.Atoi(fields[1])
+			if err != nil || lut.Size < 2 || lut.Size > maxLUTSize {
+				return nil, fmt.Errorf("line %d: invalid LUT_3D_SIZE %s", lineNumber, fields[1])
+			}
+			lut.Data = make([]float32, 0, lut.Size*lut.Size*lut.Size*3)
+		case "DOMAIN_MIN", "DOMAIN_MAX":
+			values, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNumber, err.Error())
+			}
+			for channel := range values {
+				if fields[0] == "DOMAIN_MIN" {
+					lut.DomainMin[channel] = values[channel]
+				} else {
+					lut.DomainMax[channel] = values[channel]
+				}
+			}
+		case "LUT_3D_INPUT_RANGE":
+			values, err := parseFloats(fields[1:], 2)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNumber, err.Error())
+			}
+			lut.DomainMin = [3]float64{values[0], values[0], values[0]}
+			lut.DomainMax = [3]float64{values[1], values[1], values[1]}
+		default:
+			if lut.Size == 0 {
+				return nil, fmt.Errorf("line %d: data before LUT_3D_SIZE", lineNumber)
+			}
+			values, err := parseFloats(fields, 3)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNumber, err.Error())
+			}
+			for _, value := range values {
+				lut.Data = append(lut.Data, float32(value))
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lut.Size == 0 {
+		return nil, errors.New("no LUT_3D_SIZE in file")
+	}
+	if len(lut.Data) != lut.Size*lut.Size*lut.Size*3 {
+		return nil, fmt.Errorf("expected %d entries, found %d", lut.Size*lut.Size*lut.Size, len(lut.Data)/3)
+	}
+	for channel := 0; channel < 3; channel++ {
+		if lut.DomainMax[channel] <= lut.DomainMin[channel] {
+			return nil, errors.New("invalid domain")
+		}
+	}
+	return &lut, nil
+}
+
+func parseFloats(fields []string, count int) ([]float64, error) {
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d values, found %d", count, len(fields))
+	}
+	values := make([]float64, count)
+	for idx, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %s", field)
+		}
+		values[idx] = value
+	}
+	return values, nil
+}
+
+// lutAxis is where each 8 bit channel value falls between two LUT entries
+type lutAxis struct {
+	index [256]int
+	frac  [256]float32
+}
+
+func (l *LUT3D) axis(channel int) *lutAxis {
+	var axis lutAxis
+	domain := l.DomainMax[channel] - l.DomainMin[channel]
+	for value := 0; value < 256; value++ {
+		position := (float64(value)/255 - l.DomainMin[channel]) / domain * float64(l.Size-1)
+		position = math.Max(0, math.Min(float64(l.Size-1), position))
+		index := int(position)
+		if index == l.Size-1 {
+			index--
+		}
+		axis.index[value] = index
+		axis.frac[value] = float32(position - float64(index))
+	}
+	return &axis
+}
+
+// Apply grades a BGR frame in place, interpolating between LUT entries. Strength 1 applies the full grade.
+func (l *LUT3D) Apply(frame *gocv.Mat, strength float64) {
+	if frame.Type() != gocv.MatTypeCV8UC3 {
+		return
+	}
+	data := frame.DataPtrUint8()
+	if len(data) != frame.Rows()*frame.Cols()*3 {
+		log.Printf("can't grade a frame that isn't continuous")
+		return
+	}
+	axes := [3]*lutAxis{l.axis(0), l.axis(1), l.axis(2)}
+
+	// rows are graded in parallel, a full HD frame is two million lookups
+	workers := runtime.NumCPU()
+	rowsPerWorker := (frame.Rows() + workers - 1) / workers
+	rowBytes := frame.Cols() * 3
+	var wg sync.WaitGroup
+	for start := 0; start < frame.Rows(); start += rowsPerWorker {
+		end := start + rowsPerWorker
+		if end > frame.Rows() {
+			end = frame.Rows()
+		}
+		wg.Add(1)
+		go func(pixels []byte) {
+			defer wg.Done()
+			l.grade(pixels, axes, float32(strength))
+		}(data[start*rowBytes : end*rowBytes])
+	}
+	wg.Wait()
+}
+
+// grade maps BGR pixels through the LUT with trilinear interpolation
+func (l *LUT3D) grade(pixels []byte, axes [3]*lutAxis, strength float32) {
+	// distance between neighbouring entries along the red, green and blue axes
+	dr, dg, db := 3, l.Size*3, l.Size*l.Size*3
+	var graded [3]float32
+	for offset := 0; offset+2 < len(pixels); offset += 3 {
+		b, g, r := pixels[offset], pixels[offset+1], pixels[offset+2]
+		rf, gf, bf := axes[0].frac[r], axes[1].frac[g], axes[2].frac[b]
+		base := axes[2].index[b]*db + axes[1].index[g]*dg + axes[0].index[r]*dr
+		for channel := 0; channel < 3; channel++ {
+			at := base + channel
+			c00 := l.Data[at] + (l.Data[at+dr]-l.Data[at])*rf
+			c10 := l.Data[at+dg] + (l.Data[at+dg+dr]-l.Data[at+dg])*rf
+			c01 := l.Data[at+db] + (l.Data[at+db+dr]-l.Data[at+db])*rf
+			c11 := l.Data[at+db+dg] + (l.Data[at+db+dg+dr]-l.Data[at+db+dg])*rf
+			c0 := c00 + (c10-c00)*gf
+			c1 := c01 + (c11-c01)*gf
+			graded[channel] = (c0 + (c1-c0)*bf) * 255
+		}
+		// graded is RGB, the frame BGR
+		for channel, original := range [3]byte{r, g, b} {
+			value := float32(original) + (graded[channel]-float32(original))*strength
+			pixels[offset+2-channel] = byte(math.Max(0, math.Min(255, float64(value)+0.5)))
+		}
+	}
+}
+
+// a loaded LUT, or why it couldn't be loaded
+type cachedLUT struct {
+	lut *LUT3D
+	err error
+}
+
+// the LUTs loaded so far by path. The live view applies one to every frame while exports and re-renders may grade
+// with another, so each stays loaded rather than replacing the last.
+var lutCache = struct {
+	lock sync.Mutex
+	luts map[string]cachedLUT
+}{luts: map[string]cachedLUT{}}
+
+// CachedLUT loads the LUT at path, or returns it from the cache if it was loaded before
+func CachedLUT(path string) (*LUT3D, error) {
+	lutCache.lock.Lock()
+	cached, ok := lutCache.luts[path]
+	lutCache.lock.Unlock()
+	if ok {
+		return cached.lut, cached.err
+	}
+	return ReloadLUT(path)
+}
+
+// ReloadLUT reads the LUT at path again, picking up changes to the file, and caches it
+func ReloadLUT(path string) (*LUT3D, error) {
+	lut, err := LoadCubeLUT(path)
+	if err != nil {
+		log.Printf("error loading LUT %s: %s", filepath.Base(path), err.Error())
+	}
+	lutCache.lock.Lock()
+	defer lutCache.lock.Unlock()
+	lutCache.luts[path] = cachedLUT{lut: lut, err: err}
+	return lut, err
+}
+
+// ApplyLUT grades frame with the project's LUT if it is enabled for the given stage of the pipeline
+func ApplyLUT(settings LUTSettings, mode LUTMode, frame *gocv.Mat) {
+	if !settings.Enabled || settings.Path == "" || settings.Mode != mode {
+		return
+	}
+	lut, err := CachedLUT(settings.Path)
+	if err != nil {
+		return
+	}
+	lut.Apply(frame, settings.Strength)
+}
@@ -0,0 +1,205 @@
+package backend
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// identityCube is a 2x2x2 LUT that leaves colours as they are
+const identityCube = `# comment
+TITLE "Identity"
+LUT_3D_SIZE 2
+
+0 0 0
+1 0 0
+0 1 0
+1 1 0
+0 0 1
+1 0 1
+0 1 1
+1 1 1
+`
+
+func writeCube(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "lut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "test.cube")
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCubeLUT(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		size      int
+		title     string
+		domainMin [3]float64
+		domainMax [3]float64
+	}{
+		{"identity", identityCube, 2, "Identity", [3]float64{0, 0, 0}, [3]float64{1, 1, 1}},
+		{"crlf", strings.Replace(identityCube, "\n", "\r\n", -1), 2, "Identity", [3]float64{0, 0, 0}, [3]float64{1, 1, 1}},
+		{"domain", "DOMAIN_MIN 0 0.1 0.2\nDOMAIN_MAX 1 2 3\n" + identityCube, 2, "Identity",
+			[3]float64{0, 0.1, 0.2}, [3]float64{1, 2, 3}},
+		{"input range", "LUT_3D_INPUT_RANGE 0 4095\n" + identityCube, 2, "Identity",
+			[3]float64{0, 0, 0}, [3]float64{4095, 4095, 4095}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lut, err := LoadCubeLUT(writeCube(t, test.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if lut.Size != test.size {
+				t.Errorf("size %d, want %d", lut.Size, test.size)
+			}
+			if lut.Title != test.title {
+				t.Errorf("title %q, want %q", lut.Title, test.title)
+			}
+			if len(lut.Data) != test.size*test.size*test.size*3 {
+				t.Errorf("%d values, want %d", len(lut.Data), test.size*test.size*test.size*3)
+			}
+			if lut.DomainMin != test.domainMin || lut.DomainMax != test.domainMax {
+				t.Errorf("domain %v..%v, want %v..%v", lut.DomainMin, lut.DomainMax, test.domainMin, test.domainMax)
+			}
+		})
+	}
+}
+
+func TestLoadCubeLUTErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", ""},
+		{"1d", "LUT_1D_SIZE 2\n0 0 0\n1 1 1\n"},
+		{"size too small", "LUT_3D_SIZE 1\n0 0 0\n"},
+		{"size too large", "LUT_3D_SIZE 257\n"},
+		{"size not a number", "LUT_3D_SIZE two\n"},
+		{"data before size", "0 0 0\nLUT_3D_SIZE 2\n"},
+		{"short row", strings.Replace(identityCube, "1 1 1\n", "1 1\n", 1)},
+		{"long row", strings.Replace(identityCube, "1 1 1\n", "1 1 1 1\n", 1)},
+		{"not a number", strings.Replace(identityCube, "1 1 1\n", "1 x 1\n", 1)},
+		{"missing rows", strings.Replace(identityCube, "1 1 1\n", "", 1)},
+		{"extra rows", identityCube + "1 1 1\n"},
+		{"empty domain", "DOMAIN_MIN 0 0 0\nDOMAIN_MAX 1 0 1\n" + identityCube},
+		{"short domain", "DOMAIN_MAX 1 1\n" + identityCube},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadCubeLUT(writeCube(t, test.content))
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestCachedLUTKeepsEachPath(t *testing.T) {
+	livePath, exportPath := writeCube(t, identityCube), writeCube(t, identityCube)
+	live, err := CachedLUT(livePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	export, err := CachedLUT(exportPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	// the files are gone, so anything that isn't served from the cache fails to load
+	os.Remove(livePath)
+	os.Remove(exportPath)
+	if again, err := CachedLUT(livePath); err != nil || again != live {
+		t.Error("the live view's LUT was loaded again after another one was used")
+	}
+	if again, err := CachedLUT(exportPath); err != nil || again != export {
+		t.Error("the export's LUT was loaded again")
+	}
+	if _, err := ReloadLUT(livePath); err == nil {
+		t.Error("reloading a removed LUT didn't fail")
+	}
+	if _, err := CachedLUT(livePath); err == nil {
+		t.Error("a failed reload wasn't cached")
+	}
+}
+
+// identityLUT returns a LUT of the given size that leaves colours as they are
+func identityLUT(size int) *LUT3D {
+	lut := &LUT3D{Size: size, DomainMax: [3]float64{1, 1, 1}}
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				scale := float32(size - 1)
+				lut.Data = append(lut.Data, float32(r)/scale, float32(g)/scale, float32(b)/scale)
+			}
+		}
+	}
+	return lut
+}
+
+// gradePixels grades packed BGR pixels the way Apply grades each row of a frame
+func gradePixels(lut *LUT3D, pixels []byte, strength float64) []byte {
+	graded := append([]byte{}, pixels...)
+	lut.grade(graded, [3]*lutAxis{lut.axis(0), lut.axis(1), lut.axis(2)}, float32(strength))
+	return graded
+}
+
+func TestLUTGradeIdentity(t *testing.T) {
+	pixels := make([]byte, 0)
+	for b := 0; b < 256; b += 15 {
+		for g := 0; g < 256; g += 15 {
+			for r := 0; r < 256; r += 15 {
+				pixels = append(pixels, byte(b), byte(g), byte(r))
+			}
+		}
+	}
+	pixels = append(pixels, 255, 255, 255, 1, 128, 254)
+	for _, size := range []int{2, 5, 17} {
+		graded := gradePixels(identityLUT(size), pixels, 1)
+		for offset := 0; offset < len(pixels); offset += 3 {
+			if !bytes.Equal(graded[offset:offset+3], pixels[offset:offset+3]) {
+				t.Errorf("size %d identity graded BGR %v to %v", size, pixels[offset:offset+3], graded[offset:offset+3])
+				break
+			}
+		}
+	}
+}
+
+func TestLUTGrade(t *testing.T) {
+	// the identity with pure red turned to pure blue
+	path := writeCube(t, strings.Replace(identityCube, "1 0 0\n", "0 0 1\n", 1))
+	lut, err := LoadCubeLUT(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	tests := []struct {
+		name     string
+		pixel    []byte
+		strength float64
+		want     []byte
+	}{
+		// pixels are BGR, the LUT RGB
+		{"red", []byte{0, 0, 255}, 1, []byte{255, 0, 0}},
+		{"half red is interpolated", []byte{0, 0, 128}, 1, []byte{128, 0, 0}},
+		{"white", []byte{255, 255, 255}, 1, []byte{255, 255, 255}},
+		{"green", []byte{0, 255, 0}, 1, []byte{0, 255, 0}},
+		{"no strength", []byte{0, 0, 255}, 0, []byte{0, 0, 255}},
+		{"half strength", []byte{0, 0, 255}, 0.5, []byte{128, 0, 128}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if graded := gradePixels(lut, test.pixel, test.strength); !bytes.Equal(graded, test.want) {
+				t.Errorf("graded BGR %v to %v, want %v", test.pixel, graded, test.want)
+			}
+		})
+	}
+}
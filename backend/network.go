@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// network cameras get device ids above the numbered local devices
+const NetworkCameraBaseID = 100
+
+var (
+	// device id -> stream URL, guarded by cameraLock
+	networkCameras = map[int]string{}
+
+	// device id -> how long the last reads blocked, guarded by cameraLock
+	readLatencies = map[int]time.Duration{}
+)
+
+// AddNetworkCamera registers an MJPEG (http/https) or RTSP stream as a capture source and returns its device id.
+// Adding a URL that is already registered returns the existing id. Ids depend on the order streams are added, so
+// projects refer to network cameras by URL.
+func AddNetworkCamera(streamURL string) (int, error) {
+	parsedURL, err := url.Parse(strings.TrimSpace(streamURL))
+	if err != nil {
+		return -1, fmt.Errorf("invalid camera URL %s due to: %s", streamURL, err)
+	}
+	switch parsedURL.Scheme {
+	case "http", "https", "rtsp":
+	default:
+		return -1, fmt.Errorf("unsupported camera URL %s. use http(s):// for MJPEG or rtsp://", streamURL)
+	}
+	if parsedURL.Host == "" {
+		return -1, fmt.Errorf("camera URL %s has no host", streamURL)
+	}
+
+	cameraLock.Lock()
+	defer cameraLock.Unlock()
+	for deviceID, existingURL := range networkCameras {
+		if existingURL == parsedURL.String() {
+			return deviceID, nil
+		}
+	}
+	deviceID := NetworkCameraBaseID + len(networkCameras)
+	networkCameras[deviceID] = parsedURL.String()
+	log.Printf("registered network cam %d at %s", deviceID, parsedURL.String())
+	return deviceID, nil
+}
+
+// NetworkCameraURL returns the stream URL of a network camera, or "" for a local device
+func NetworkCameraURL(deviceID int) string {
+	cameraLock.Lock()
+	defer cameraLock.Unlock()
+	return networkCameras[deviceID]
+}
+
+// NetworkCameraID returns the device id the stream at streamURL was added with this session
+func NetworkCameraID(streamURL string) (int, bool) {
+	cameraLock.Lock()
+	defer cameraLock.Unlock()
+	for deviceID, existingURL := range networkCameras {
+		if existingURL == streamURL {
+			return deviceID, true
+		}
+	}
+	return -1, false
+}
+
+func IsNetworkCamera(deviceID int) bool {
+	return deviceID >= NetworkCameraBaseID
+}
+
+// CameraReadLatency is a smoothed measure of how long reading a frame blocks. For network cameras this is
+// dominated by transport and decoding, so it's shown to the user as the stream latency.
+func CameraReadLatency(deviceID int) time.Duration {
+	cameraLock.Lock()
+	defer cameraLock.Unlock()
+	return readLatencies[deviceID]
+}
+
+// recordReadLatency must be called with cameraLock held
+func recordReadLatency(deviceID int, latency time.Duration) {
+	previous, ok := readLatencies[deviceID]
+	if !ok {
+		readLatencies[deviceID] = latency
+		return
+	}
+	readLatencies[deviceID] = (previous*7 + latency) / 8
+}
@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"../util"
+)
+
+// KeyPreset is a named chroma key setup, saved with a project or shared by all of them
+type KeyPreset struct {
+	Name     string
+	Settings KeySettings
+}
+
+// KeyPresetLibrary holds the presets shared by all projects
+type KeyPresetLibrary struct {
+	Presets []KeyPreset
+	// applied when the app starts, before a project is opened
+	Default string
+}
+
+func keyPresetLibraryPath() (string, error) {
+	baseDir, err := util.GetMocapBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`%s\keypresets.json`, baseDir), nil
+}
+
+// LoadKeyPresetLibrary reads the shared presets. The library is empty until a preset is shared.
+func LoadKeyPresetLibrary() (KeyPresetLibrary, error) {
+	library := KeyPresetLibrary{}
+	fullPath, err := keyPresetLibraryPath()
+	if err != nil {
+		return library, err
+	}
+	fileBytes, err := ioutil.ReadFile(fullPath)
+	if os.IsNotExist(err) {
+		return library, nil
+	}
+	if err != nil {
+		return library, err
+	}
+	err = json.Unmarshal(fileBytes, &library)
+	return library, err
+}
+
+// Save writes the shared presets
+func (l KeyPresetLibrary) Save() error {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	err = util.MkRelativeDir("")
+	if err != nil {
+		return err
+	}
+	fullPath, err := keyPresetLibraryPath()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fullPath, bytes, os.ModePerm)
+}
+
+// FindKeyPreset returns the preset called name
+func FindKeyPreset(presets []KeyPreset, name string) (KeyPreset, bool) {
+	for _, preset := range presets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return KeyPreset{}, false
+}
+
+// WithKeyPreset returns a copy of presets with preset added, replacing the one with the same name
+func WithKeyPreset(presets []KeyPreset, preset KeyPreset) []KeyPreset {
+	updated := WithoutKeyPreset(presets, preset.Name)
+	return append(updated, preset)
+}
+
+// WithoutKeyPreset returns a copy of presets without the one called name
+func WithoutKeyPreset(presets []KeyPreset, name string) []KeyPreset {
+	updated := make([]KeyPreset, 0, len(presets)+1)
+	for _, preset := range presets {
+		if preset.Name != name {
+			updated = append(updated, preset)
+		}
+	}
+	return updated
+}
+
+// DefaultKeyPresetSettings returns the key settings of the project's default preset, looked up in the project's own
+// presets first and then in the shared ones
+func (s *ProjectSettings) DefaultKeyPresetSettings(library KeyPresetLibrary) (KeySettings, bool) {
+	if s.DefaultKeyPreset == "" {
+		return KeySettings{}, false
+	}
+	preset, ok := FindKeyPreset(s.KeyPresets, s.DefaultKeyPreset)
+	if !ok {
+		preset, ok = FindKeyPreset(library.Presets, s.DefaultKeyPreset)
+	}
+	return preset.Settings, ok
+}
@@ -0,0 +1,197 @@
+package backend
+
+import (
+	"fmt"
+	"gocv.io/x/gocv"
+	"image"
+	"log"
+)
+
+// KeyMode is the key a snapshot is rendered with
+type KeyMode int
+
+const (
+	KeyNone KeyMode = iota
+	KeyChroma
+	KeyDifference
+	KeyLuma
+)
+
+var KeyModes = []KeyMode{KeyNone, KeyChroma, KeyDifference, KeyLuma}
+
+func (m KeyMode) String() string {
+	switch m {
+	case KeyChroma:
+		return "Chroma Key"
+	case KeyDifference:
+		return "Difference Key"
+	case KeyLuma:
+		return "Luma Key"
+	}
+	return "No Key"
+}
+
+// RenderSettings are everything a raw camera frame is turned into a saved frame with, kept with each snapshot so it
+// can be rendered again later
+type RenderSettings struct {
+	Key           KeyMode
+	ChromaKey     KeySettings
+	DifferenceKey DifferenceKeySettings
+	LumaKey       LumaKeySettings
+	GarbageMattes []GarbageMatte
+	Background    BackgroundSettings
+	Layers        []LayerSettings
+	// embedded, so frames shot before panning was added still read their zoom
+	Framing
+	Filters []FilterSettings
+	LUT     LUTSettings
+}
+
+// RenderSettings returns the project's current settings for rendering with key and framing
+func (s *ProjectSettings) RenderSettings(key KeyMode, framing Framing) RenderSettings {
+	return RenderSettings{
+		Key:           key,
+		ChromaKey:     s.Key,
+		DifferenceKey: s.DifferenceKey,
+		LumaKey:       s.LumaKey,
+		GarbageMattes: s.GarbageMattes,
+		Background:    s.Background,
+		Layers:        s.Layers,
+		Framing:       framing,
+		Filters:       s.Filters,
+		LUT:           s.LUT,
+	}
+}
+
+// RenderSources are what rendering a raw camera frame draws on besides the frame itself. Snapshots take them from
+// what the live view has loaded, Renderer opens them from the files the settings name.
+type RenderSources interface {
+	// RenderBackground copies the background for animation frame index at the capture resolution, with the layers
+	// behind the subject on it, into dst
+	RenderBackground(index int, settings RenderSettings, dst *gocv.Mat)
+	// ApplyLayers composites the visible layers with the given placement onto dst for animation frame index
+	ApplyLayers(index int, settings RenderSettings, placement LayerPlacement, dst *gocv.Mat)
+	// CleanPlate copies the clean plate at the capture resolution into dst, false if there is none
+	CleanPlate(dst *gocv.Mat) bool
+}
+
+// RenderFrame renders raw, the camera frame of animation frame index, into a saved frame: keyed, zoomed, layered,
+// filtered and graded. The caller must close the result.
+func RenderFrame(raw gocv.Mat, index int, settings RenderSettings, sources RenderSources) gocv.Mat {
+	frame := raw.Clone()
+	defer frame.Close()
+	ConformToResolution(&frame, CurrentResolution()) // frames shot before a resolution change
+	size := image.Pt(frame.Cols(), frame.Rows())
+	if settings.Key != KeyNone {
+		background := gocv.NewMat()
+		defer background.Close()
+		sources.RenderBackground(index, settings, &background)
+		garbage := GarbageMask{Mattes: settings.GarbageMattes, Region: image.Rect(0, 0, size.X, size.Y), FrameSize: size}
+		keyed := gocv.NewMat()
+		defer keyed.Close()
+		switch settings.Key {
+		case KeyChroma:
+			ApplyKey(frame, background, settings.ChromaKey, garbage, false, &keyed)
+		case KeyDifference:
+			plate := gocv.NewMat()
+			defer plate.Close()
+			if sources.CleanPlate(&plate) {
+				ApplyDifferenceKey(frame, plate, background, settings.DifferenceKey, garbage, false, &keyed)
+			}
+		case KeyLuma:
+			ApplyLumaKey(frame, background, settings.LumaKey, garbage, false, &keyed)
+		}
+		if !keyed.Empty() {
+			keyed.CopyTo(&frame)
+		}
+	}
+	zoomed := gocv.NewMat()
+	Zoom(frame, settings.Framing.Rect(size.X, size.Y), &zoomed)
+	sources.ApplyLayers(index, settings, LayerInFront, &zoomed)
+	ApplyFilters(settings.Filters, &zoomed)
+	ApplyLUT(settings.LUT, LUTModeCapture, &zoomed)
+	return zoomed
+}
+
+// Renderer renders raw camera frames outside the live view, to render frames again and to export them. It keeps the
+// backgrounds, layers and clean plate it opens for the frames that follow, so it should be closed when done.
+type Renderer struct {
+	// backgrounds by path, nil for the ones that couldn't be opened
+	backgrounds map[string]FrameSource
+	layers      LayerStack
+	plate       gocv.Mat
+	plateRead   bool
+}
+
+func NewRenderer() *Renderer {
+	return &Renderer{backgrounds: make(map[string]FrameSource), plate: gocv.NewMat()}
+}
+
+func (r *Renderer) Close() {
+	for _, source := range r.backgrounds {
+		if source != nil {
+			source.Close()
+		}
+	}
+	r.layers.Close()
+	r.plate.Close()
+}
+
+// background opens the background at path, unless it already is
+func (r *Renderer) background(path string, sequence bool) FrameSource {
+	key := fmt.Sprintf("%t %s", sequence, path)
+	if source, ok := r.backgrounds[key]; ok {
+		return source
+	}
+	var source FrameSource
+	var err error
+	if sequence && !IsVideo(path) {
+		source, err = OpenImageSequence(path)
+	} else {
+		source, err = OpenFrameSource(path)
+	}
+	if err != nil {
+		log.Printf("error opening %s: %s", path, err.Error())
+		source = nil
+	}
+	r.backgrounds[key] = source
+	return source
+}
+
+func (r *Renderer) RenderBackground(index int, settings RenderSettings, dst *gocv.Mat) {
+	var source FrameSource
+	if settings.Background.Path != "" {
+		source = r.background(settings.Background.Path, settings.Background.Sequence)
+	}
+	RenderBackground(source, index, settings.Background, CurrentResolution().Point(), dst)
+	r.ApplyLayers(index, settings, LayerBehind, dst)
+}
+
+func (r *Renderer) ApplyLayers(index int, settings RenderSettings, placement LayerPlacement, dst *gocv.Mat) {
+	r.layers.Open(settings.Layers)
+	r.layers.Apply(index, settings.Layers, placement, dst)
+}
+
+func (r *Renderer) CleanPlate(dst *gocv.Mat) bool {
+	if !r.plateRead {
+		r.plateRead = true
+		plateFileName, err := CleanPlatePath(Backend.Name)
+		if err == nil {
+			r.plate.Close()
+			r.plate = gocv.IMRead(plateFileName, gocv.IMReadColor) // empty if the project has no plate
+		}
+		if !r.plate.Empty() {
+			ConformToResolution(&r.plate, CurrentResolution()) // plates shot before a resolution change
+		}
+	}
+	if r.plate.Empty() {
+		return false
+	}
+	r.plate.CopyTo(dst)
+	return true
+}
+
+// Render renders raw, the camera frame of animation frame index, with settings. The caller must close the result.
+func (r *Renderer) Render(raw gocv.Mat, index int, settings RenderSettings) gocv.Mat {
+	return RenderFrame(raw, index, settings, r)
+}
@@ -0,0 +1,274 @@
+package backend
+
+import (
+	"fmt"
+	"image"
+)
+
+// Resolution is the size of captured frames. The aspect ratio is implied by width and height.
+type Resolution struct {
+	Width  int
+	Height int
+}
+
+var DefaultResolution = Resolution{Width: 1280, Height: 720}
+
+// Resolutions are the capture resolutions offered to the user
+var Resolutions = []Resolution{
+	{Width: 1280, Height: 720},
+	{Width: 1920, Height: 1080},
+	{Width: 3840, Height: 2160},
+	{Width: 640, Height: 480},
+	{Width: 1024, Height: 768},
+	{Width: 1440, Height: 1080},
+	{Width: 720, Height: 720},
+	{Width: 1080, Height: 1080},
+}
+
+func (r Resolution) String() string {
+	return fmt.Sprintf("%dx%d (%s)", r.Width, r.Height, r.AspectRatio())
+}
+
+func (r Resolution) AspectRatio() string {
+	divisor := gcd(r.Width, r.Height)
+	if divisor == 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%d:%d", r.Width/divisor, r.Height/divisor)
+}
+
+func (r Resolution) IsValid() bool {
+	return r.Width > 0 && r.Height > 0
+}
+
+func (r Resolution) Point() image.Point {
+	return image.Pt(r.Width, r.Height)
+}
+
+// FitInto returns the largest size with this resolution's aspect ratio that fits in a maxWidth x maxHeight box
+func (r Resolution) FitInto(maxWidth int, maxHeight int) (int, int) {
+	if !r.IsValid() {
+		return maxWidth, maxHeight
+	}
+	if r.Width*maxHeight > r.Height*maxWidth {
+		return maxWidth, maxWidth * r.Height / r.Width
+	}
+	return maxHeight * r.Width / r.Height, maxHeight
+}
+
+func gcd(a int, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// ProjectSettings are saved along with the frames of a project
+type ProjectSettings struct {
+	Resolution Resolution
+	Camera     CameraSettings
+
+	// stream URLs of network cameras used by the project
+	NetworkCameras []string
+
+	// number of consecutive camera frames stacked into each snapshot to reduce noise
+	SnapshotFrames   int
+	SnapshotStacking StackMode
+
+	// cameras that capture an additional angle with every snapshot. Network cameras are kept by stream URL, as their
+	// device ids are handed out in the order the streams are added each session.
+	AngleCameras        []int
+	AngleNetworkCameras []string `json:",omitempty"`
+
+	Timelapse TimelapseSettings
+	OnionSkin OnionSkinSettings
+	Guides    GuideSettings
+	Reference ReferenceSettings
+	// what the keys composite the subject onto
+	Background BackgroundSettings
+	// images, sequences and videos composited with the subject, bottom first
+	Layers []LayerSettings
+	// the region of the camera frame snapshots show, and the camera move exports follow
+	Framing    Framing
+	CameraMove CameraMoveSettings
+
+	// filters applied in order to the live view and snapshots
+	Filters []FilterSettings
+	LUT     LUTSettings
+
+	Key           KeySettings
+	DifferenceKey DifferenceKeySettings
+	LumaKey       LumaKeySettings
+	// polygons every key treats as always background or always foreground
+	GarbageMattes []GarbageMatte
+
+	// named chroma key setups saved with the project
+	KeyPresets []KeyPreset
+	// the preset Key is reset to when the project is opened, none keeps the key as it was last saved
+	DefaultKeyPreset string
+}
+
+// OnionSkinSettings control the neighbouring frames ghosted over the live view
+type OnionSkinSettings struct {
+	Enabled  bool
+	Previous int
+	Next     int
+	// opacity of the nearest frame, each frame further away is Falloff times as opaque as the one before
+	Opacity float64
+	Falloff float64
+	// tints previous frames red and next frames green to tell them apart
+	Tint bool
+}
+
+const MaxOnionSkinFrames = 5
+
+// GuideSettings are the composition guides drawn over the live view. They never appear in snapshots.
+type GuideSettings struct {
+	RuleOfThirds bool
+	CenterCross  bool
+	// grid spacing in pixels of the captured frame, 0 for no grid
+	GridSpacing int
+	ActionSafe  bool
+	TitleSafe   bool
+	// width / height of the framing to mask the live view to, 0 for no mask
+	MaskAspect float64
+}
+
+// BlendMode is how an overlay is combined with the image under it
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendDifference
+	BlendAdd
+	BlendLighten
+	BlendDarken
+)
+
+var BlendModes = []BlendMode{BlendNormal, BlendDifference, BlendAdd, BlendLighten, BlendDarken}
+
+func (m BlendMode) String() string {
+	switch m {
+	case BlendDifference:
+		return "Difference"
+	case BlendAdd:
+		return "Add"
+	case BlendLighten:
+		return "Lighten"
+	case BlendDarken:
+		return "Darken"
+	}
+	return "Normal"
+}
+
+// ReferenceSettings place a reference image or video over the live view for tracing. It never appears in snapshots.
+type ReferenceSettings struct {
+	Enabled bool
+	Path    string
+	Opacity float64
+	Blend   BlendMode
+	// Scale is relative to the reference fitted into the frame, offsets are fractions of the frame size
+	Scale   float64
+	OffsetX float64
+	OffsetY float64
+	// video frame shown for the first animation frame
+	FrameOffset int
+}
+
+var defaultReference = ReferenceSettings{Opacity: 0.5, Scale: 1}
+
+var defaultLUT = LUTSettings{Strength: 1}
+
+var defaultOnionSkin = OnionSkinSettings{Previous: 1, Opacity: 0.5, Falloff: 0.5}
+
+func NewProjectSettings() ProjectSettings {
+	return ProjectSettings{
+		Resolution:     DefaultResolution,
+		SnapshotFrames: 1,
+		Timelapse:      TimelapseSettings{IntervalSeconds: defaultTimelapseInterval},
+		OnionSkin:      defaultOnionSkin,
+		Reference:      defaultReference,
+		Framing:        DefaultFraming,
+		LUT:            defaultLUT,
+		Key:            DefaultKeySettings,
+		DifferenceKey:  DefaultDifferenceKeySettings,
+		LumaKey:        DefaultLumaKeySettings,
+	}
+}
+
+// applyDefaults fills in settings missing from projects saved by older versions
+func (s *ProjectSettings) applyDefaults() {
+	if !s.Resolution.IsValid() {
+		s.Resolution = DefaultResolution
+	}
+	if s.SnapshotFrames < 1 {
+		s.SnapshotFrames = 1
+	}
+	if s.Timelapse.IntervalSeconds < 1 {
+		s.Timelapse.IntervalSeconds = defaultTimelapseInterval
+	}
+	if s.OnionSkin.Opacity == 0 && s.OnionSkin.Falloff == 0 {
+		s.OnionSkin = defaultOnionSkin
+	}
+	if s.Reference.Scale == 0 {
+		s.Reference = defaultReference
+	}
+	if s.Framing.Zoom < 1 {
+		s.Framing.Zoom = DefaultFraming.Zoom
+	}
+	if s.LUT.Strength == 0 {
+		s.LUT.Strength = defaultLUT.Strength
+	}
+	if s.Key == (KeySettings{}) {
+		s.Key = DefaultKeySettings
+	}
+	if s.DifferenceKey.Threshold == 0 {
+		s.DifferenceKey = DefaultDifferenceKeySettings
+	}
+	if s.LumaKey == (LumaKeySettings{}) {
+		s.LumaKey = DefaultLumaKeySettings
+	}
+}
+
+// AngleCameraIDs returns the device ids of the angle cameras, leaving out network cameras not added this session
+func (s ProjectSettings) AngleCameraIDs() []int {
+	deviceIDs := append([]int{}, s.AngleCameras...)
+	for _, streamURL := range s.AngleNetworkCameras {
+		if deviceID, ok := NetworkCameraID(streamURL); ok {
+			deviceIDs = append(deviceIDs, deviceID)
+		}
+	}
+	return deviceIDs
+}
+
+// SetAngleCamera adds or removes deviceID from the angle cameras
+func (s *ProjectSettings) SetAngleCamera(deviceID int, enabled bool) {
+	if IsNetworkCamera(deviceID) {
+		streamURL := NetworkCameraURL(deviceID)
+		streamURLs := make([]string, 0)
+		for _, angleURL := range s.AngleNetworkCameras {
+			if angleURL != streamURL {
+				streamURLs = append(streamURLs, angleURL)
+			}
+		}
+		if enabled {
+			streamURLs = append(streamURLs, streamURL)
+		}
+		s.AngleNetworkCameras = streamURLs
+		return
+	}
+	deviceIDs := make([]int, 0)
+	for _, angleCamera := range s.AngleCameras {
+		if angleCamera != deviceID {
+			deviceIDs = append(deviceIDs, angleCamera)
+		}
+	}
+	if enabled {
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	s.AngleCameras = deviceIDs
+}
+
+func CurrentResolution() Resolution {
+	return Backend.CurrentSettings().Resolution
+}
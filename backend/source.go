@@ -0,0 +1,264 @@
+package backend
+
+import (
+	"fmt"
+	"gocv.io/x/gocv"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ImageExtensions = []string{".png", ".jpg", ".jpeg", ".bmp", ".tif", ".tiff", ".webp"}
+	VideoExtensions = []string{".mp4", ".mov", ".avi", ".mkv", ".webm", ".wmv"}
+)
+
+// FrameSource is a still image, an image sequence or a video that supplies a frame per animation frame,
+// used for reference overlays, backgrounds and layers
+type FrameSource interface {
+	// Frame copies frame index into dst. Indices past the end show the last frame.
+	Frame(index int, dst *gocv.Mat) bool
+	FrameCount() int
+	Close()
+}
+
+func hasExtension(path string, extensions []string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	for _, test := range extensions {
+		if extension == test {
+			return true
+		}
+	}
+	return false
+}
+
+// IsVideo reports whether path is a video file, judging by its extension
+func IsVideo(path string) bool {
+	return hasExtension(path, VideoExtensions)
+}
+
+// OpenFrameSource opens path as an image or a video depending on its extension
+func OpenFrameSource(path string) (FrameSource, error) {
+	return openFrameSource(path, gocv.IMReadColor)
+}
+
+// OpenLayerSource opens path like OpenFrameSource, or as the numbered sequence it belongs to if sequence is set.
+// Images with transparency keep it as a fourth channel, BGRA.
+func OpenLayerSource(path string, sequence bool) (FrameSource, error) {
+	if sequence && !IsVideo(path) {
+		return openImageSequence(path, gocv.IMReadUnchanged)
+	}
+	return openFrameSource(path, gocv.IMReadUnchanged)
+}
+
+// readImage reads an 8 bit BGR image, or BGRA if flags keep the transparency of images that have it
+func readImage(path string, flags gocv.IMReadFlag) (gocv.Mat, bool) {
+	image := gocv.IMRead(path, flags)
+	if flags == gocv.IMReadUnchanged && !image.Empty() {
+		switch image.Type() {
+		case gocv.MatTypeCV8UC1:
+			gocv.CvtColor(image, &image, gocv.ColorGrayToBGR)
+		case gocv.MatTypeCV8UC3, gocv.MatTypeCV8UC4:
+		default: // 16 bit images, read again without their transparency
+			image.Close()
+			image = gocv.IMRead(path, gocv.IMReadColor)
+		}
+	}
+	if image.Empty() {
+		image.Close()
+		return image, false
+	}
+	return image, true
+}
+
+func openFrameSource(path string, flags gocv.IMReadFlag) (FrameSource, error) {
+	if hasExtension(path, ImageExtensions) {
+		image, ok := readImage(path, flags)
+		if !ok {
+			return nil, fmt.Errorf("couldn't read image %s", path)
+		}
+		return &imageSource{image: image}, nil
+	}
+	if IsVideo(path) {
+		video, err := gocv.VideoCaptureFile(path)
+		if err != nil {
+			return nil, err
+		}
+		source := &videoSource{video: video, frameCount: int(video.Get(gocv.VideoCaptureFrameCount)), current: gocv.NewMat(), currentIndex: -1, failedIndex: -1}
+		if source.frameCount < 1 {
+			source.Close()
+			return nil, fmt.Errorf("couldn't read frames from video %s", path)
+		}
+		return source, nil
+	}
+	return nil, fmt.Errorf("%s is not a supported image or video file", filepath.Base(path))
+}
+
+type imageSource struct {
+	image gocv.Mat
+}
+
+func (s *imageSource) Frame(index int, dst *gocv.Mat) bool {
+	s.image.CopyTo(dst)
+	return true
+}
+
+func (s *imageSource) FrameCount() int {
+	return 1
+}
+
+func (s *imageSource) Close() {
+	s.image.Close()
+}
+
+// videoSource decodes frames on demand and keeps the last one, since the same frame is usually asked for repeatedly.
+// A frame that failed to decode isn't tried again until another frame was asked for.
+type videoSource struct {
+	lock         sync.Mutex
+	video        *gocv.VideoCapture
+	frameCount   int
+	current      gocv.Mat
+	currentIndex int
+	failedIndex  int
+}
+
+func (s *videoSource) Frame(index int, dst *gocv.Mat) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if index >= s.frameCount {
+		index = s.frameCount - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index == s.failedIndex {
+		return false
+	}
+	s.failedIndex = -1
+	if index != s.currentIndex {
+		// reading on is much faster than seeking, but after a failed read the decoder may be anywhere
+		if s.currentIndex < 0 || index != s.currentIndex+1 {
+			s.video.Set(gocv.VideoCapturePosFrames, float64(index))
+		}
+		if !s.video.Read(&s.current) || s.current.Empty() {
+			log.Printf("couldn't read video frame %d", index)
+			s.currentIndex = -1
+			s.failedIndex = index
+			return false
+		}
+		s.currentIndex = index
+	}
+	s.current.CopyTo(dst)
+	return true
+}
+
+func (s *videoSource) FrameCount() int {
+	return s.frameCount
+}
+
+func (s *videoSource) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.video.Close()
+	s.current.Close()
+}
+
+// OpenImageSequence opens the numbered images path is one of, like shot_0001.png, shot_0002.png and so on
+func OpenImageSequence(path string) (FrameSource, error) {
+	return openImageSequence(path, gocv.IMReadColor)
+}
+
+func openImageSequence(path string, flags gocv.IMReadFlag) (FrameSource, error) {
+	dir, base := filepath.Split(path)
+	extension := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, extension)
+	prefix := strings.TrimRight(stem, "0123456789")
+	if prefix == stem {
+		return nil, fmt.Errorf("%s isn't numbered, sequences are named like shot_0001.png", base)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type numberedFile struct {
+		number int
+		path   string
+	}
+	numbered := make([]numberedFile, 0)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.EqualFold(filepath.Ext(name), extension) || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(name, filepath.Ext(name))[len(prefix):])
+		if err != nil || number < 0 {
+			continue
+		}
+		numbered = append(numbered, numberedFile{number: number, path: filepath.Join(dir, name)})
+	}
+	if len(numbered) < 2 {
+		return nil, fmt.Errorf("found no other images numbered like %s", base)
+	}
+	sort.Slice(numbered, func(i, j int) bool {
+		return numbered[i].number < numbered[j].number
+	})
+	paths := make([]string, 0, len(numbered))
+	for _, file := range numbered {
+		paths = append(paths, file.path)
+	}
+	return &sequenceSource{paths: paths, flags: flags, current: gocv.NewMat(), currentIndex: -1, failedIndex: -1}, nil
+}
+
+// sequenceSource reads images on demand and keeps the last one, since the same frame is usually asked for repeatedly.
+// An image that failed to read isn't tried again until another one was asked for.
+type sequenceSource struct {
+	lock         sync.Mutex
+	paths        []string
+	flags        gocv.IMReadFlag
+	current      gocv.Mat
+	currentIndex int
+	failedIndex  int
+}
+
+func (s *sequenceSource) Frame(index int, dst *gocv.Mat) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if index >= len(s.paths) {
+		index = len(s.paths) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index == s.failedIndex {
+		return false
+	}
+	s.failedIndex = -1
+	if index != s.currentIndex {
+		image, ok := readImage(s.paths[index], s.flags)
+		if !ok {
+			log.Printf("couldn't read sequence image %s", s.paths[index])
+			s.currentIndex = -1
+			s.failedIndex = index
+			return false
+		}
+		s.current.Close()
+		s.current = image
+		s.currentIndex = index
+	}
+	s.current.CopyTo(dst)
+	return true
+}
+
+func (s *sequenceSource) FrameCount() int {
+	return len(s.paths)
+}
+
+func (s *sequenceSource) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.current.Close()
+}
@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"errors"
+	"gocv.io/x/gocv"
+	"time"
+
+	"../util"
+)
+
+// StackMode is how several exposures of the same scene are combined into one frame
+type StackMode int
+
+const (
+	StackModeMean StackMode = iota
+	StackModeMedian
+)
+
+const MaxSnapshotFrames = 16
+
+func (m StackMode) String() string {
+	if m == StackModeMedian {
+		return "Median"
+	}
+	return "Average"
+}
+
+// StackFrames combines frames of the same size and type into dst, averaging out sensor noise.
+// The median rejects outliers such as a hand passing through one of the frames at the cost of speed.
+func StackFrames(frames []gocv.Mat, mode StackMode, dst *gocv.Mat) error {
+	defer util.LogPerf("StackFrames()", time.Now())
+	if len(frames) == 0 {
+		return errors.New("no frames to stack")
+	}
+	if len(frames) == 1 {
+		frames[0].CopyTo(dst)
+		return nil
+	}
+	for _, frame := range frames[1:] {
+		if frame.Rows() != frames[0].Rows() || frame.Cols() != frames[0].Cols() || frame.Type() != frames[0].Type() {
+			return errors.New("can't stack frames of different sizes")
+		}
+	}
+
+	switch mode {
+	case StackModeMedian:
+		return medianStack(frames, dst)
+	default:
+		meanStack(frames, dst)
+		return nil
+	}
+}
+
+func meanStack(frames []gocv.Mat, dst *gocv.Mat) {
+	sum := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(0, 0, 0, 0), frames[0].Rows(), frames[0].Cols(), gocv.MatTypeCV32FC3)
+	defer sum.Close()
+	frameFloat := gocv.NewMat()
+	defer frameFloat.Close()
+	for _, frame := range frames {
+		frame.ConvertTo(&frameFloat, gocv.MatTypeCV32FC3)
+		gocv.Add(sum, frameFloat, &sum)
+	}
+	sum.DivideFloat(float32(len(frames)))
+	sum.ConvertTo(dst, gocv.MatTypeCV8UC3)
+}
+
+func medianStack(frames []gocv.Mat, dst *gocv.Mat) error {
+	frameBytes := make([][]byte, len(frames))
+	for idx, frame := range frames {
+		frameBytes[idx] = frame.ToBytes()
+	}
+	stacked := make([]byte, len(frameBytes[0]))
+	samples := make([]byte, len(frames))
+	for offset := range stacked {
+		// insertion sort; there are at most MaxSnapshotFrames samples per pixel
+		for idx := range frameBytes {
+			value := frameBytes[idx][offset]
+			pos := idx
+			for pos > 0 && samples[pos-1] > value {
+				samples[pos] = samples[pos-1]
+				pos--
+			}
+			samples[pos] = value
+		}
+		stacked[offset] = samples[len(samples)/2]
+	}
+	result, err := gocv.NewMatFromBytes(frames[0].Rows(), frames[0].Cols(), frames[0].Type(), stacked)
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+	result.CopyTo(dst)
+	return nil
+}
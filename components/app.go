@@ -5,6 +5,7 @@ import (
 	"../config"
 	"fmt"
 	"fyne.io/fyne"
+	"fyne.io/fyne/driver/desktop"
 	"fyne.io/fyne/layout"
 )
 
@@ -49,9 +50,14 @@ func NewMocapAppWindow(mocapApp fyne.App) *MocapAppWindow {
 	rootContainer := fyne.NewContainerWithLayout(rootLayout, AnimationTopComponent.Container, AnimationFilmStripComponent.Container, AnimationBottomComponent.Container)
 
 	// start capturing
+	backend.StartCapture()
 	go AnimationTopComponent.CaptureLoop()
 
 	appWindow.SetContent(rootContainer)
+	if keyCanvas, ok := appWindow.Canvas().(desktop.Canvas); ok {
+		keyCanvas.SetOnKeyDown(AnimationTopComponent.FlipControl.OnKeyDown)
+		keyCanvas.SetOnKeyUp(AnimationTopComponent.FlipControl.OnKeyUp)
+	}
 	mocapAppWindow.Window = &appWindow
 
 	return &mocapAppWindow
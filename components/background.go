@@ -0,0 +1,327 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/canvas"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/storage"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"../backend"
+)
+
+const (
+	backgroundDisplayWidth  = 480
+	backgroundDisplayHeight = 270
+)
+
+// BackgroundPanel chooses what the keys composite the subject onto: a still, an image sequence or a video that
+// advances with the animation, fitted to the frame, under the layers placed behind the subject
+type BackgroundPanel struct {
+	Container *fyne.Container
+
+	BackgroundImage  *canvas.Image
+	PathLabel        *widget.Label
+	SequenceToggle   *widget.Check
+	LoopToggle       *widget.Check
+	FitSelect        *widget.Select
+	OffsetXControl   *SliderControl
+	OffsetYControl   *SliderControl
+	FrameOffsetEntry *widget.Entry
+
+	layers *LayerPanel
+
+	// the loaded background, replaced from the UI while the capture loop keys against it
+	lock   sync.Mutex
+	source backend.FrameSource
+	// the background fitted to the capture resolution with the layers behind the subject on it, and the animation
+	// frame it is for. -1 rebuilds it.
+	resized      gocv.Mat
+	index        int
+	resizedCount int
+	// the zoomed region of resized at live view size, rebuilt when either changes
+	previewBackground      gocv.Mat
+	previewBackgroundCount int
+	previewBackgroundRect  image.Rectangle
+	previewBackgroundSize  image.Point
+}
+
+// Refresh shows the project's background file and how it is fitted, panned and synced to the animation
+func (b *BackgroundPanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().Background
+	b.refreshPathLabel()
+	b.SequenceToggle.Checked = settings.Sequence
+	b.SequenceToggle.Refresh()
+	b.LoopToggle.Checked = settings.Loop
+	b.LoopToggle.Refresh()
+	b.FitSelect.Selected = settings.Fit.String()
+	b.FitSelect.Refresh()
+	b.OffsetXControl.SetValue(settings.OffsetX * 100)
+	b.OffsetYControl.SetValue(settings.OffsetY * 100)
+	if b.FrameOffsetEntry.Text != strconv.Itoa(settings.FrameOffset) {
+		b.FrameOffsetEntry.SetText(strconv.Itoa(settings.FrameOffset))
+	}
+}
+
+func (b *BackgroundPanel) refreshPathLabel() {
+	path := backend.Backend.CurrentSettings().Background.Path
+	if path == "" {
+		b.PathLabel.SetText("Plain white background")
+		return
+	}
+	b.lock.Lock()
+	source := b.source
+	b.lock.Unlock()
+	if source == nil {
+		b.PathLabel.SetText(fmt.Sprintf("%s (missing)", filepath.Base(path)))
+		return
+	}
+	b.PathLabel.SetText(fmt.Sprintf("%s (%d frames)", filepath.Base(path), source.FrameCount()))
+}
+
+// RefreshDisplay shows the current background frame in the panel
+func (b *BackgroundPanel) RefreshDisplay() {
+	index := 0
+	if AnimationFilmStripComponent != nil { // the panel is built before the film strip
+		index = AnimationFilmStripComponent.InsertionIndex()
+	}
+	b.lock.Lock()
+	b.update(index)
+	width, height := backend.CurrentResolution().FitInto(backgroundDisplayWidth, backgroundDisplayHeight)
+	display := gocv.NewMat()
+	gocv.Resize(b.resized, &display, image.Pt(width, height), 0, 0, gocv.InterpolationArea)
+	b.lock.Unlock()
+	defer display.Close()
+	img, err := display.ToImage()
+	if err != nil {
+		log.Printf("error: %s", err.Error())
+		return
+	}
+	b.BackgroundImage.Image = img
+	canvas.Refresh(b.BackgroundImage)
+}
+
+// Open loads the background at path, replacing the current one. Images are opened as the numbered sequence they
+// belong to if sequence is set. An empty path shows a plain white background.
+func (b *BackgroundPanel) Open(path string, sequence bool) error {
+	var source backend.FrameSource
+	if path != "" {
+		var err error
+		if sequence && !backend.IsVideo(path) {
+			source, err = backend.OpenImageSequence(path)
+		} else {
+			source, err = backend.OpenFrameSource(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	b.lock.Lock()
+	if b.source != nil {
+		b.source.Close()
+	}
+	b.source = source
+	b.index = -1
+	b.lock.Unlock()
+	return nil
+}
+
+// ApplyProjectSettings reopens the project's background, if any
+func (b *BackgroundPanel) ApplyProjectSettings() {
+	settings := backend.Backend.CurrentSettings().Background
+	err := b.Open(settings.Path, settings.Sequence)
+	if err != nil {
+		log.Printf("error opening background: %s", err.Error())
+	}
+	b.Refresh()
+	b.RefreshDisplay()
+}
+
+func (b *BackgroundPanel) OpenFileDialog() {
+	win := fyne.CurrentApp().Driver().AllWindows()[0]
+	open := dialog.NewFileOpen(func(read fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		if read == nil {
+			return
+		}
+		defer read.Close()
+		fileName := read.URI().String()[len(read.URI().Scheme())+3:] // remove "file://"
+		sequence := b.SequenceToggle.Checked
+		err = b.Open(fileName, sequence)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		b.updateSettings(func(settings *backend.BackgroundSettings) {
+			settings.Path = fileName
+			settings.Sequence = sequence
+		})
+		b.Refresh()
+	}, win)
+
+	extensions := append(append([]string{}, backend.ImageExtensions...), backend.VideoExtensions...)
+	open.SetFilter(storage.NewExtensionFileFilter(extensions))
+	open.Show()
+}
+
+// Invalidate rebuilds the background the next time it is used, after the resolution or its settings change
+func (b *BackgroundPanel) Invalidate() {
+	b.lock.Lock()
+	b.index = -1
+	b.lock.Unlock()
+}
+
+// update fits the background frame for an animation frame to the capture resolution and composites the layers
+// behind the subject onto it, unless that is already done. Must be called with the lock held.
+func (b *BackgroundPanel) update(index int) {
+	if index == b.index && !b.resized.Empty() {
+		return
+	}
+	backend.RenderBackground(b.source, index, backend.Backend.CurrentSettings().Background, backend.CurrentResolution().Point(), &b.resized)
+	b.layers.ApplyBehind(index, &b.resized)
+	b.index = index
+	b.resizedCount++
+}
+
+// Background copies the background for an animation frame at the capture resolution into dst
+func (b *BackgroundPanel) Background(index int, dst *gocv.Mat) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.update(index)
+	b.resized.CopyTo(dst)
+}
+
+// PreviewBackground returns the region rect of the background for an animation frame scaled to size, to key the
+// live view against
+func (b *BackgroundPanel) PreviewBackground(index int, rect image.Rectangle, size image.Point) gocv.Mat {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.update(index)
+	if b.previewBackgroundCount == b.resizedCount && b.previewBackgroundRect == rect && b.previewBackgroundSize == size {
+		return b.previewBackground
+	}
+	region := b.resized.Region(rect)
+	gocv.Resize(region, &b.previewBackground, size, 0, 0, gocv.InterpolationLinear)
+	region.Close()
+	b.previewBackgroundCount = b.resizedCount
+	b.previewBackgroundRect = rect
+	b.previewBackgroundSize = size
+	return b.previewBackground
+}
+
+// updateSettings changes the project's background settings, saves them and shows the result
+func (b *BackgroundPanel) updateSettings(update func(settings *backend.BackgroundSettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.Background)
+	})
+	SaveProjectSettings()
+	b.Invalidate()
+	b.RefreshDisplay()
+}
+
+func NewBackgroundPanel(layers *LayerPanel) *BackgroundPanel {
+	backgroundPanel := BackgroundPanel{
+		BackgroundImage:   &canvas.Image{FillMode: canvas.ImageFillContain},
+		PathLabel:         widget.NewLabel(""),
+		FrameOffsetEntry:  widget.NewEntry(),
+		layers:            layers,
+		resized:           gocv.NewMat(),
+		index:             -1,
+		previewBackground: gocv.NewMat(),
+	}
+	backgroundPanel.BackgroundImage.SetMinSize(fyne.NewSize(backgroundDisplayWidth, backgroundDisplayHeight))
+	loadButton := widget.NewButton("Load Background", func() {
+		backgroundPanel.OpenFileDialog()
+	})
+	clearButton := widget.NewButton("Clear", func() {
+		_ = backgroundPanel.Open("", false)
+		backgroundPanel.updateSettings(func(settings *backend.BackgroundSettings) {
+			settings.Path = ""
+		})
+		backgroundPanel.Refresh()
+	})
+	backgroundPanel.SequenceToggle = widget.NewCheck("", func(flag bool) {
+		current := backend.Backend.CurrentSettings().Background
+		path := current.Path
+		if flag == current.Sequence {
+			return
+		}
+		if path != "" {
+			err := backgroundPanel.Open(path, flag)
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				backgroundPanel.Refresh()
+				return
+			}
+		}
+		backgroundPanel.updateSettings(func(settings *backend.BackgroundSettings) {
+			settings.Sequence = flag
+		})
+		backgroundPanel.refreshPathLabel()
+	})
+	backgroundPanel.LoopToggle = widget.NewCheck("", func(flag bool) {
+		backgroundPanel.updateSettings(func(settings *backend.BackgroundSettings) {
+			settings.Loop = flag
+		})
+	})
+	fitNames := make([]string, 0)
+	for _, mode := range backend.BackgroundFitModes {
+		fitNames = append(fitNames, mode.String())
+	}
+	backgroundPanel.FitSelect = widget.NewSelect(fitNames, func(choice string) {
+		for _, mode := range backend.BackgroundFitModes {
+			if mode.String() == choice && mode != backend.Backend.CurrentSettings().Background.Fit {
+				backgroundPanel.updateSettings(func(settings *backend.BackgroundSettings) {
+					settings.Fit = mode
+				})
+			}
+		}
+	})
+	// pans a background cropped by the fit across the frame
+	backgroundPanel.OffsetXControl = NewSliderControl("X", "%.0f%%", -100, 100, 1, func(value float64) {
+		backgroundPanel.updateSettings(func(settings *backend.BackgroundSettings) {
+			settings.OffsetX = value / 100
+		})
+	})
+	backgroundPanel.OffsetYControl = NewSliderControl("Y", "%.0f%%", -100, 100, 1, func(value float64) {
+		backgroundPanel.updateSettings(func(settings *backend.BackgroundSettings) {
+			settings.OffsetY = value / 100
+		})
+	})
+	backgroundPanel.FrameOffsetEntry.OnChanged = func(text string) {
+		frameOffset, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || frameOffset == backend.Backend.CurrentSettings().Background.FrameOffset {
+			return
+		}
+		backgroundPanel.updateSettings(func(settings *backend.BackgroundSettings) {
+			settings.FrameOffset = frameOffset
+		})
+	}
+
+	loadGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), loadButton, clearButton, backgroundPanel.PathLabel)
+	controlsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Image Sequence"), backgroundPanel.SequenceToggle,
+		widget.NewLabel("Loop"), backgroundPanel.LoopToggle,
+		widget.NewLabel("Fit"), backgroundPanel.FitSelect,
+		backgroundPanel.OffsetXControl.Label, backgroundPanel.OffsetXControl.Slider,
+		backgroundPanel.OffsetYControl.Label, backgroundPanel.OffsetYControl.Slider,
+		widget.NewLabel("Frame Offset"), backgroundPanel.FrameOffsetEntry)
+	backgroundPanel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), loadGroup, backgroundPanel.BackgroundImage, controlsGroup)
+
+	backgroundPanel.Refresh()
+	backgroundPanel.RefreshDisplay()
+
+	return &backgroundPanel
+}
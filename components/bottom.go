@@ -11,6 +11,7 @@ import (
 	"image/color"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "image/png"
@@ -54,8 +55,13 @@ func (f *Player) On() {
 		if f.frameNum >= len(backend.Backend.Frames) {
 			f.frameNum = 0
 		}
-		fileName := backend.Backend.Frames[f.frameNum].Filename
+		fileName, _, ok := backend.Backend.Frames[f.frameNum].Track(AnimationFilmStripComponent.Track)
+		if !ok { // not shot by this track, hold the previous frame for its time
+			time.Sleep(f.sleepTime)
+			continue
+		}
 		AnimationBottomComponent.PreviewImage = canvas.NewImageFromFile(fileName)
+		AnimationBottomComponent.PreviewImage.FillMode = canvas.ImageFillContain
 		AnimationBottomComponent.PreviewImageContainer.Objects[0] = AnimationBottomComponent.PreviewImage
 		AnimationBottomComponent.PreviewImage.SetMinSize(fyne.NewSize(config.WebcamDisplayWidth, config.WebcamDisplayHeight))
 		AnimationBottomComponent.PreviewImageContainer.Refresh()
@@ -75,31 +81,65 @@ func (f *Player) Rewind() {
 	f.frameNum = 0
 }
 
+// GenerateVideo exports one video per track: the main camera plus every additional angle
 func (f *Player) GenerateVideo() {
-	log.Printf("todo: generate video")
 	baseDir, err := util.GetMocapBaseDir()
 	if err != nil {
 		log.Printf("error getting basedir: %s", err.Error())
 		return
 	}
 	timestampSuffix := time.Now().Format("2006-01-02-15:04")
-	absPath := fmt.Sprintf(`%s\%s\%s-%s.mp4`, baseDir, backend.Backend.Name, backend.Backend.Name, timestampSuffix)
-	vw, err := gocv.VideoWriterFile(absPath, "mp4v", float64(f.Fps),config.WebcamCaptureWidth, config.WebcamCaptureHeight, true)
-	if err != nil {
-		log.Printf("error getting video writer: %s", err.Error())
+
+	appWindow := *MocapApp.Window
+	progressBar := dialog.NewProgress("Generating Video", "Please wait while generating video.", appWindow)
+	tracks := backend.Backend.Tracks()
+	savedPaths := make([]string, 0)
+	for trackIdx, track := range tracks {
+		absPath := fmt.Sprintf(`%s\%s\%s-%s.mp4`, baseDir, backend.Backend.Name, backend.Backend.Name, timestampSuffix)
+		if track != backend.TrackMain {
+			trackSuffix := strings.ToLower(strings.ReplaceAll(trackName(track), " ", ""))
+			absPath = fmt.Sprintf(`%s\%s\%s-%s-%s.mp4`, baseDir, backend.Backend.Name, backend.Backend.Name, timestampSuffix, trackSuffix)
+		}
+		pinnedTrackIdx := trackIdx
+		err = f.generateTrackVideo(track, absPath, func(progress float64) {
+			progressBar.SetValue((float64(pinnedTrackIdx) + progress) / float64(len(tracks)))
+		})
+		if err != nil {
+			log.Printf("error generating video for %s: %s", trackName(track), err.Error())
+			continue
+		}
+		savedPaths = append(savedPaths, absPath)
+	}
+	progressBar.SetValue(1.0)
+	progressBar.Hide()
+	if len(savedPaths) == 1 {
+		DisplayUserTip(fmt.Sprintf("Video file is saved at:\n%s", savedPaths[0]))
 		return
 	}
+	DisplayUserTip(fmt.Sprintf("Video files are saved at:\n%s", strings.Join(savedPaths, "\n")))
+}
+
+func (f *Player) generateTrackVideo(track int, absPath string, setProgress func(float64)) error {
+	res := backend.CurrentResolution()
+	vw, err := gocv.VideoWriterFile(absPath, "mp4v", float64(f.Fps), res.Width, res.Height, true)
+	if err != nil {
+		return err
+	}
 	log.Printf("video file=%s", absPath)
 	defer vw.Close()
-
-	appWindow := *MocapApp.Window
-	progressBar := dialog.NewProgress("Generating Video", "Please wait while generating video.", appWindow)
-	for idx, frame := range backend.Backend.Frames {
-		srcMat := gocv.IMRead(frame.Filename, gocv.IMReadColor)
-		if srcMat.Empty() {
-			log.Printf("couldn't read frame from %s", frame.Filename)
+	renderer := backend.NewRenderer()
+	defer renderer.Close()
+
+	frames := backend.Backend.FrameList()
+	// every frame is graded alike, even if the LUT is changed while the video is written
+	lut := backend.Backend.CurrentSettings().LUT
+	for idx, frame := range frames {
+		srcMat, ok := exportFrame(renderer, track, idx, frame)
+		if !ok {
 			continue
 		}
+		backend.ConformToResolution(&srcMat, res) // frames shot before a resolution change
+		backend.ApplyLUT(lut, backend.LUTModeExport, &srcMat)
 		log.Printf("writing %dx%d frame %d", srcMat.Size()[1], srcMat.Size()[0], idx)
 		err = vw.Write(srcMat)
 		if err != nil {
@@ -109,11 +149,45 @@ func (f *Player) GenerateVideo() {
 		if err != nil {
 			log.Printf("error closing frame: %s", err.Error())
 		}
-		progressBar.SetValue(float64(idx)/float64(len(backend.Backend.Frames)))
+		setProgress(float64(idx) / float64(len(frames)))
 	}
-	progressBar.SetValue(1.0)
-	progressBar.Hide()
-	DisplayUserTip(fmt.Sprintf("Video file is saved at:\n%s", absPath))
+	return nil
+}
+
+// exportFrame reads the image of a frame on a track for export. Main camera frames are rendered again from their raw
+// camera frame when they have one, so exports always come from the originals. The caller must close the result.
+func exportFrame(renderer *backend.Renderer, track int, idx int, frame *backend.Frame) (gocv.Mat, bool) {
+	fileName, _, ok := frame.Track(track)
+	if !ok {
+		log.Printf("frame %d has no image from %s", idx, trackName(track))
+		return gocv.Mat{}, false
+	}
+	if track == backend.TrackMain {
+		move := backend.Backend.CurrentSettings().CameraMove
+		settings, ok := backend.Backend.FrameRender(frame)
+		if ok {
+			raw := gocv.IMRead(frame.RawFilename, gocv.IMReadColor)
+			defer raw.Close()
+			if !raw.Empty() {
+				if move.Enabled {
+					if framing, ok := move.FramingAt(idx); ok {
+						settings.Framing = framing
+					}
+				}
+				return renderer.Render(raw, idx, settings), true
+			}
+			log.Printf("couldn't read raw frame from %s, exporting the saved frame", frame.RawFilename)
+		} else if move.Enabled {
+			log.Printf("frame %d has no raw camera frame, exporting it without the camera move", idx)
+		}
+	}
+	srcMat := gocv.IMRead(fileName, gocv.IMReadColor)
+	if srcMat.Empty() {
+		log.Printf("couldn't read frame from %s", fileName)
+		srcMat.Close()
+		return gocv.Mat{}, false
+	}
+	return srcMat, true
 }
 
 func (f *Player) SetFPS(fps int) {
@@ -202,7 +276,11 @@ func NewBottomComponent() *BottomComponent {
 		log.Printf("generate button clicked")
 		component.Player.GenerateVideo()
 	})
-	toolbarContainer := fyne.NewContainerWithLayout(toolbarLayout, playButton, stopButton, rewindButton, fpsSelectEntry, fpsLabelContainer, generateVideoButton)
+	reRenderButton := widget.NewButton("Re-render", func() {
+		log.Printf("re-render button clicked")
+		AnimationTopComponent.OpenReRenderDialog()
+	})
+	toolbarContainer := fyne.NewContainerWithLayout(toolbarLayout, playButton, stopButton, rewindButton, fpsSelectEntry, fpsLabelContainer, generateVideoButton, reRenderButton)
 
 	rootLayout := layout.NewHBoxLayout()
 
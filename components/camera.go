@@ -0,0 +1,262 @@
+package components
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"log"
+
+	"../backend"
+	"../util"
+)
+
+type CameraPanel struct {
+	Container        *fyne.Container
+	ResolutionSelect *widget.Select
+
+	LockToggle             *widget.Check
+	AutoExposureToggle     *widget.Check
+	AutoFocusToggle        *widget.Check
+	AutoWhiteBalanceToggle *widget.Check
+
+	ExposureControl     *SliderControl
+	GainControl         *SliderControl
+	FocusControl        *SliderControl
+	WhiteBalanceControl *SliderControl
+	BrightnessControl   *SliderControl
+	ContrastControl     *SliderControl
+
+	SnapshotFramesControl *SliderControl
+	StackingSelect        *widget.Select
+
+	// one check per open camera that should capture an additional angle with every snapshot
+	AngleCamerasContainer *fyne.Container
+}
+
+// newCameraControl returns a slider for one manual camera setting, update sets it and turns off the automatic mode
+// that would override it
+func (p *CameraPanel) newCameraControl(name string, min float64, max float64, step float64, update func(settings *backend.CameraSettings, value float64)) *SliderControl {
+	return NewSliderControl(name, "%.0f", min, max, step, func(value float64) {
+		backend.UpdateCameraSettings(func(settings *backend.CameraSettings) {
+			update(settings, value)
+		})
+		SaveProjectSettings()
+		p.refreshToggles()
+	})
+}
+
+// Refresh shows the current camera's resolution and settings, or what the driver reports while they were never
+// changed, and the project's snapshot stacking and angle cameras
+func (p *CameraPanel) Refresh() {
+	p.ResolutionSelect.Selected = backend.CurrentResolution().String()
+	p.ResolutionSelect.Refresh()
+
+	settings := p.currentSettings()
+	p.ExposureControl.SetValue(settings.Exposure)
+	p.GainControl.SetValue(settings.Gain)
+	p.FocusControl.SetValue(settings.Focus)
+	p.WhiteBalanceControl.SetValue(settings.WhiteBalance)
+	p.BrightnessControl.SetValue(settings.Brightness)
+	p.ContrastControl.SetValue(settings.Contrast)
+	p.refreshToggles()
+
+	projectSettings := backend.Backend.CurrentSettings()
+	p.SnapshotFramesControl.SetValue(float64(projectSettings.SnapshotFrames))
+	p.StackingSelect.Selected = projectSettings.SnapshotStacking.String()
+	p.StackingSelect.Refresh()
+	p.RefreshAngleCameras()
+}
+
+// RefreshAngleCameras rebuilds the additional angle checks from the currently open cameras
+func (p *CameraPanel) RefreshAngleCameras() {
+	objects := []fyne.CanvasObject{widget.NewLabel("Also Capture")}
+	angleCameraIDs := backend.Backend.CurrentSettings().AngleCameraIDs()
+	for _, deviceID := range backend.OpenCameraIDs() {
+		if deviceID == backend.CurrentWebcamID {
+			continue
+		}
+		pinnedDeviceID := deviceID
+		angleToggle := widget.NewCheck(cameraName(deviceID), func(flag bool) {
+			p.SetAngleCamera(pinnedDeviceID, flag)
+		})
+		angleToggle.Checked = util.ContainsInt(angleCameraIDs, deviceID)
+		objects = append(objects, angleToggle)
+	}
+	if len(objects) == 1 {
+		objects = append(objects, widget.NewLabel("no other cameras open"))
+	}
+	p.AngleCamerasContainer.Objects = objects
+	p.AngleCamerasContainer.Refresh()
+}
+
+// SetAngleCamera adds or removes deviceID from the cameras captured alongside the current one
+func (p *CameraPanel) SetAngleCamera(deviceID int, enabled bool) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		settings.SetAngleCamera(deviceID, enabled)
+	})
+	log.Printf("angle cameras=%v", backend.Backend.CurrentSettings().AngleCameraIDs())
+	SaveProjectSettings()
+}
+
+func (p *CameraPanel) refreshToggles() {
+	settings := p.currentSettings()
+	p.LockToggle.Checked = settings.Locked
+	p.AutoExposureToggle.Checked = settings.AutoExposure && !settings.Locked
+	p.AutoFocusToggle.Checked = settings.AutoFocus && !settings.Locked
+	p.AutoWhiteBalanceToggle.Checked = settings.AutoWhiteBalance && !settings.Locked
+	p.LockToggle.Refresh()
+	p.AutoExposureToggle.Refresh()
+	p.AutoFocusToggle.Refresh()
+	p.AutoWhiteBalanceToggle.Refresh()
+}
+
+// currentSettings returns the project's camera settings, or what the camera is using if they were never touched
+func (p *CameraPanel) currentSettings() backend.CameraSettings {
+	settings := backend.Backend.CurrentSettings().Camera
+	if !settings.Enabled && backend.CurrentCamera() != nil {
+		settings = backend.ReadCameraSettings(backend.CurrentCamera())
+	}
+	return settings
+}
+
+// Lock freezes whatever the automatic controls have settled on, so consecutive snapshots match
+func (p *CameraPanel) Lock(lock bool) {
+	if backend.CurrentCamera() == nil {
+		DisplayUserTip("Please connect a camera first.")
+		return
+	}
+	// read before the settings are locked for the change, which the capture loop waits on
+	current := backend.ReadCameraSettings(backend.CurrentCamera())
+	backend.UpdateCameraSettings(func(settings *backend.CameraSettings) {
+		if lock {
+			settings.Exposure = current.Exposure
+			settings.Gain = current.Gain
+			settings.Focus = current.Focus
+			settings.WhiteBalance = current.WhiteBalance
+		}
+		settings.Locked = lock
+	})
+	log.Printf("camera settings locked=%t", lock)
+	SaveProjectSettings()
+	p.Refresh()
+}
+
+func NewCameraPanel(component *TopComponent) *CameraPanel {
+	cameraPanel := CameraPanel{}
+
+	resolutionNames := make([]string, 0)
+	for _, res := range backend.Resolutions {
+		resolutionNames = append(resolutionNames, res.String())
+	}
+	cameraPanel.ResolutionSelect = widget.NewSelect(resolutionNames, func(choice string) {
+		for _, res := range backend.Resolutions {
+			if res.String() != choice || res == backend.CurrentResolution() {
+				continue
+			}
+			component.SetResolution(res)
+			SaveProjectSettings()
+			return
+		}
+		log.Printf("resolution %s is already in use", choice)
+	})
+	cameraPanel.ResolutionSelect.Selected = backend.CurrentResolution().String()
+
+	// exposure is in log2 seconds and white balance in kelvin, as reported by most webcam drivers
+	cameraPanel.ExposureControl = cameraPanel.newCameraControl("Exposure", -13, 0, 1, func(settings *backend.CameraSettings, value float64) {
+		settings.AutoExposure = false
+		settings.Exposure = value
+	})
+	cameraPanel.GainControl = cameraPanel.newCameraControl("Gain", 0, 255, 1, func(settings *backend.CameraSettings, value float64) {
+		settings.AutoExposure = false
+		settings.Gain = value
+	})
+	cameraPanel.FocusControl = cameraPanel.newCameraControl("Focus", 0, 255, 5, func(settings *backend.CameraSettings, value float64) {
+		settings.AutoFocus = false
+		settings.Focus = value
+	})
+	cameraPanel.WhiteBalanceControl = cameraPanel.newCameraControl("White Balance", 2000, 10000, 100, func(settings *backend.CameraSettings, value float64) {
+		settings.AutoWhiteBalance = false
+		settings.WhiteBalance = value
+	})
+	cameraPanel.BrightnessControl = cameraPanel.newCameraControl("Brightness", 0, 255, 1, func(settings *backend.CameraSettings, value float64) {
+		settings.Brightness = value
+	})
+	cameraPanel.ContrastControl = cameraPanel.newCameraControl("Contrast", 0, 255, 1, func(settings *backend.CameraSettings, value float64) {
+		settings.Contrast = value
+	})
+
+	cameraPanel.LockToggle = widget.NewCheck("", func(flag bool) {
+		cameraPanel.Lock(flag)
+	})
+	cameraPanel.AutoExposureToggle = widget.NewCheck("", func(flag bool) {
+		backend.UpdateCameraSettings(func(settings *backend.CameraSettings) {
+			settings.AutoExposure = flag
+			settings.Locked = settings.Locked && !flag
+		})
+		SaveProjectSettings()
+		cameraPanel.refreshToggles()
+	})
+	cameraPanel.AutoFocusToggle = widget.NewCheck("", func(flag bool) {
+		backend.UpdateCameraSettings(func(settings *backend.CameraSettings) {
+			settings.AutoFocus = flag
+			settings.Locked = settings.Locked && !flag
+		})
+		SaveProjectSettings()
+		cameraPanel.refreshToggles()
+	})
+	cameraPanel.AutoWhiteBalanceToggle = widget.NewCheck("", func(flag bool) {
+		backend.UpdateCameraSettings(func(settings *backend.CameraSettings) {
+			settings.AutoWhiteBalance = flag
+			settings.Locked = settings.Locked && !flag
+		})
+		SaveProjectSettings()
+		cameraPanel.refreshToggles()
+	})
+
+	// noise reduction
+	cameraPanel.SnapshotFramesControl = NewSliderControl("Snapshot Frames", "%.0f", 1, backend.MaxSnapshotFrames, 1, func(value float64) {
+		backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+			settings.SnapshotFrames = int(value)
+		})
+		SaveProjectSettings()
+	})
+	stackModes := []backend.StackMode{backend.StackModeMean, backend.StackModeMedian}
+	stackModeNames := make([]string, 0)
+	for _, mode := range stackModes {
+		stackModeNames = append(stackModeNames, mode.String())
+	}
+	cameraPanel.StackingSelect = widget.NewSelect(stackModeNames, func(choice string) {
+		for _, mode := range stackModes {
+			if mode.String() == choice {
+				backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+					settings.SnapshotStacking = mode
+				})
+				SaveProjectSettings()
+			}
+		}
+	})
+
+	cameraPanel.AngleCamerasContainer = fyne.NewContainerWithLayout(layout.NewHBoxLayout())
+
+	resolutionGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Resolution"), cameraPanel.ResolutionSelect)
+	controlsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		cameraPanel.ExposureControl.Label, cameraPanel.ExposureControl.Slider,
+		cameraPanel.GainControl.Label, cameraPanel.GainControl.Slider,
+		cameraPanel.FocusControl.Label, cameraPanel.FocusControl.Slider,
+		cameraPanel.WhiteBalanceControl.Label, cameraPanel.WhiteBalanceControl.Slider,
+		cameraPanel.BrightnessControl.Label, cameraPanel.BrightnessControl.Slider,
+		cameraPanel.ContrastControl.Label, cameraPanel.ContrastControl.Slider)
+	snapshotFramesGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), cameraPanel.SnapshotFramesControl.Label, cameraPanel.SnapshotFramesControl.Slider)
+	stackingGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Noise Reduction"), cameraPanel.StackingSelect)
+	lockGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Lock Camera Settings"), cameraPanel.LockToggle)
+	autoGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(),
+		widget.NewLabel("Auto Exposure"), cameraPanel.AutoExposureToggle,
+		widget.NewLabel("Auto Focus"), cameraPanel.AutoFocusToggle,
+		widget.NewLabel("Auto WB"), cameraPanel.AutoWhiteBalanceToggle)
+	cameraPanel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), resolutionGroup, lockGroup, autoGroup,
+		controlsGroup, snapshotFramesGroup, stackingGroup, cameraPanel.AngleCamerasContainer)
+
+	cameraPanel.Refresh()
+
+	return &cameraPanel
+}
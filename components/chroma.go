@@ -0,0 +1,325 @@
+package components
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/canvas"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+	"log"
+
+	"../backend"
+	"../config"
+)
+
+// a click in color picker mode samples the square this many pixels around it
+const pickerClickRadius = 2
+
+type ChromaPanel struct {
+	ChromaFilterToggle *widget.Check
+	ColorPickerToggle  *widget.Check
+	MatteViewToggle    *widget.Check
+
+	RedControl   *SliderControl
+	GreenControl *SliderControl
+	BlueControl  *SliderControl
+
+	HueControl        *SliderControl
+	SaturationControl *SliderControl
+	ValueControl      *SliderControl
+	SoftnessControl   *SliderControl
+	ChokeControl      *SliderControl
+	SpillControl      *SliderControl
+
+	PresetControl *KeyPresetControl
+
+	PreviewColor *canvas.Rectangle
+
+	// shows the soft matte the tolerances key, to check the subject has no holes and the screen no specks left
+	MatteView bool
+
+	// the unkeyed live view frame the color picker samples, and how it is shown
+	pickerFrame gocv.Mat
+	pickerImage *image.RGBA
+	// the rectangle dragged over the picker, in live view pixels
+	dragging  bool
+	dragStart image.Point
+	dragEnd   image.Point
+
+	Container *fyne.Container
+}
+
+// newKeyControl returns a slider for one keyer setting, update sets it from the slider value
+func (c *ChromaPanel) newKeyControl(name string, format string, min float64, max float64, step float64, update func(settings *backend.KeySettings, value float64)) *SliderControl {
+	return NewSliderControl(name, format, min, max, step, func(value float64) {
+		c.updateSettings(func(settings *backend.KeySettings) {
+			update(settings, value)
+		})
+	})
+}
+
+// newChannelControl returns a slider for one channel of the key color, update sets it from the slider value
+func (c *ChromaPanel) newChannelControl(name string, update func(clr *color.RGBA, value uint8)) *SliderControl {
+	return NewSliderControl(name, "%.0f", 0, 255, 1, func(value float64) {
+		c.updateSettings(func(settings *backend.KeySettings) {
+			update(&settings.Color, uint8(value))
+		})
+		c.refreshPreviewColor()
+	})
+}
+
+func (c *ChromaPanel) GetChromaKey() color.Color {
+	return backend.Backend.CurrentSettings().Key.Color
+}
+
+// Refresh shows the project's key colour, tolerances, softness, choke and spill suppression
+func (c *ChromaPanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().Key
+	c.RedControl.SetValue(float64(settings.Color.R))
+	c.GreenControl.SetValue(float64(settings.Color.G))
+	c.BlueControl.SetValue(float64(settings.Color.B))
+	c.HueControl.SetValue(settings.HueTolerance)
+	c.SaturationControl.SetValue(settings.SaturationTolerance)
+	c.ValueControl.SetValue(settings.ValueTolerance)
+	c.SoftnessControl.SetValue(settings.Softness)
+	c.ChokeControl.SetValue(settings.Choke)
+	c.SpillControl.SetValue(settings.SpillSuppression * 100)
+	c.refreshPreviewColor()
+}
+
+// ApplyProjectSettings keys with the project's default preset, if it has one, and syncs the panel with the project
+func (c *ChromaPanel) ApplyProjectSettings() {
+	projectSettings := backend.Backend.CurrentSettings()
+	if settings, ok := projectSettings.DefaultKeyPresetSettings(c.PresetControl.library); ok {
+		c.updateSettings(func(key *backend.KeySettings) {
+			*key = settings
+		})
+	}
+	c.PresetControl.Refresh()
+	c.Refresh()
+}
+
+func (c *ChromaPanel) refreshPreviewColor() {
+	c.PreviewColor.FillColor = c.GetChromaKey()
+	canvas.Refresh(c.PreviewColor)
+}
+
+// startColorPicker freezes the live view on an unkeyed camera frame and turns it into a hot image. A click keys the
+// colours around it, a dragged rectangle the colours inside it, and either turns the key on.
+func (c *ChromaPanel) startColorPicker(component *TopComponent) error {
+	rawMat := gocv.NewMat()
+	defer rawMat.Close()
+	err := component.captureFrame(&rawMat)
+	if err != nil {
+		return err
+	}
+	region := rawMat.Region(component.zoomRect(rawMat.Cols(), rawMat.Rows()))
+	gocv.Resize(region, &c.pickerFrame, previewSize(), 0, 0, gocv.InterpolationLinear)
+	region.Close()
+	rgbaMat := gocv.NewMat()
+	defer rgbaMat.Close()
+	component.showPreview(c.pickerFrame, &rgbaMat)
+	c.pickerImage = component.WebcamImage.Image.(*image.RGBA)
+	c.dragging = false
+	component.ZoomPanel.StopDragging()
+
+	hotImage := NewDraggableHotImageFromCanvasImage(component.WebcamImage, config.WebcamDisplayWidth, config.WebcamDisplayHeight,
+		func(s string, event *fyne.PointEvent) {
+			pt := displayToPreview(event.Position.X, event.Position.Y) // clicks are on the letterboxed canvas image
+			log.Printf("left-clicked on webcam at %#v", event.Position)
+			c.pickArea(component, image.Rect(pt.X-pickerClickRadius, pt.Y-pickerClickRadius, pt.X+pickerClickRadius+1, pt.Y+pickerClickRadius+1))
+		}, func(event *fyne.DragEvent) {
+			pt := displayToPreview(event.Position.X, event.Position.Y)
+			if !c.dragging {
+				c.dragging = true
+				c.dragStart = pt.Sub(image.Pt(event.DraggedX, event.DraggedY))
+			}
+			c.dragEnd = pt
+			c.showPickerSelection(component)
+		}, func() {
+			if !c.dragging {
+				return
+			}
+			c.dragging = false
+			area := image.Rectangle{Min: c.dragStart, Max: c.dragEnd}.Canon()
+			c.pickArea(component, image.Rect(area.Min.X, area.Min.Y, area.Max.X+1, area.Max.Y+1))
+		})
+	component.WebcamImageContainer.Objects[0] = hotImage
+	component.WebcamImageContainer.Refresh()
+	return nil
+}
+
+// showPickerSelection outlines the rectangle being dragged on the frozen live view
+func (c *ChromaPanel) showPickerSelection(component *TopComponent) {
+	img := image.NewRGBA(c.pickerImage.Bounds())
+	copy(img.Pix, c.pickerImage.Pix)
+	area := image.Rectangle{Min: c.dragStart, Max: c.dragEnd}.Canon().Intersect(img.Bounds())
+	for x := area.Min.X; x < area.Max.X; x++ {
+		img.Set(x, area.Min.Y, color.White)
+		img.Set(x, area.Max.Y-1, color.White)
+	}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		img.Set(area.Min.X, y, color.White)
+		img.Set(area.Max.X-1, y, color.White)
+	}
+	component.WebcamImage.Image = img
+	canvas.Refresh(component.WebcamImage)
+}
+
+// pickArea keys the colours in area of the frozen live view, turns the key on and lets the live view run again
+func (c *ChromaPanel) pickArea(component *TopComponent, area image.Rectangle) {
+	if !c.ColorPickerToggle.Checked {
+		return // a tap can follow the end of a drag
+	}
+	sampled := backend.SampleKey(c.pickerFrame, area, backend.Backend.CurrentSettings().Key)
+	c.updateSettings(func(settings *backend.KeySettings) {
+		*settings = sampled
+	})
+	c.Refresh()
+	log.Printf("sampled key color %v from %v", sampled.Color, area)
+
+	c.ColorPickerToggle.Checked = false
+	c.ColorPickerToggle.Refresh()
+	c.ChromaFilterToggle.Checked = true
+	c.ChromaFilterToggle.Refresh()
+	component.uncheckKeyToggles(c.ChromaFilterToggle)
+
+	component.WebcamImageContainer.Objects[0] = component.WebcamImage
+	component.WebcamImageContainer.Refresh()
+	component.SetCaptureMode(CaptureModeChromaKey)
+}
+
+// Calibrate finds the backdrop in the current camera frame and keys it
+func (c *ChromaPanel) Calibrate(component *TopComponent) error {
+	rawMat := gocv.NewMat()
+	defer rawMat.Close()
+	err := component.captureFrame(&rawMat)
+	if err != nil {
+		return err
+	}
+	region := rawMat.Region(component.zoomRect(rawMat.Cols(), rawMat.Rows()))
+	defer region.Close()
+	calibrated, err := backend.CalibrateKey(region, backend.Backend.CurrentSettings().Key)
+	if err != nil {
+		return err
+	}
+	c.updateSettings(func(settings *backend.KeySettings) {
+		*settings = calibrated
+	})
+	c.Refresh()
+	return nil
+}
+
+// updateSettings changes the project's key settings and saves them
+func (c *ChromaPanel) updateSettings(update func(settings *backend.KeySettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.Key)
+	})
+	SaveProjectSettings()
+}
+
+func NewChromaPanel(component *TopComponent) *ChromaPanel {
+	chromaPanel := ChromaPanel{
+		pickerFrame: gocv.NewMat(),
+	}
+	chromaPanel.ChromaFilterToggle = widget.NewCheck("", func(flag bool) {
+		if flag {
+			chromaPanel.ColorPickerToggle.Checked = false
+			chromaPanel.ColorPickerToggle.Refresh()
+			component.uncheckKeyToggles(chromaPanel.ChromaFilterToggle)
+			component.WebcamImageContainer.Objects[0] = component.WebcamImage
+			component.WebcamImageContainer.Refresh()
+			component.SetCaptureMode(CaptureModeChromaKey)
+		} else {
+			component.SetCaptureMode(CaptureModeNormal)
+		}
+	})
+	chromaPanel.ColorPickerToggle = widget.NewCheck("", func(flag bool) {
+		if flag {
+			component.uncheckKeyToggles(nil)
+			component.GarbageMattePanel.StopEditing() // both pick points on the live view
+			component.SetCaptureMode(CaptureModeColorPick)
+			err := chromaPanel.startColorPicker(component)
+			if err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+				chromaPanel.ColorPickerToggle.SetChecked(false)
+			}
+		} else {
+			component.SetCaptureMode(CaptureModeNormal)
+			component.WebcamImageContainer.Objects[0] = component.WebcamImage
+		}
+	})
+	chromaPanel.MatteViewToggle = widget.NewCheck("", func(flag bool) {
+		chromaPanel.MatteView = flag
+	})
+	calibrateButton := widget.NewButton("Auto Calibrate", func() {
+		err := chromaPanel.Calibrate(component)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		}
+	})
+
+	chromaPanel.PreviewColor = canvas.NewRectangle(chromaPanel.GetChromaKey())
+	chromaPanel.PreviewColor.SetMinSize(fyne.NewSize(320, 36))
+
+	chromaPanel.RedControl = chromaPanel.newChannelControl("R", func(clr *color.RGBA, value uint8) {
+		clr.R = value
+	})
+	chromaPanel.GreenControl = chromaPanel.newChannelControl("G", func(clr *color.RGBA, value uint8) {
+		clr.G = value
+	})
+	chromaPanel.BlueControl = chromaPanel.newChannelControl("B", func(clr *color.RGBA, value uint8) {
+		clr.B = value
+	})
+	chromaPanel.HueControl = chromaPanel.newKeyControl("Hue Tolerance", "%.0f", 0, 90, 1, func(settings *backend.KeySettings, value float64) {
+		settings.HueTolerance = value
+	})
+	chromaPanel.SaturationControl = chromaPanel.newKeyControl("Saturation Tolerance", "%.0f", 0, 255, 5, func(settings *backend.KeySettings, value float64) {
+		settings.SaturationTolerance = value
+	})
+	chromaPanel.ValueControl = chromaPanel.newKeyControl("Value Tolerance", "%.0f", 0, 255, 5, func(settings *backend.KeySettings, value float64) {
+		settings.ValueTolerance = value
+	})
+	chromaPanel.SoftnessControl = chromaPanel.newKeyControl("Edge Softness", "%.1f px", 0, 20, 0.5, func(settings *backend.KeySettings, value float64) {
+		settings.Softness = value
+	})
+	chromaPanel.ChokeControl = chromaPanel.newKeyControl("Choke", "%.0f px", -20, 20, 1, func(settings *backend.KeySettings, value float64) {
+		settings.Choke = value
+	})
+	chromaPanel.SpillControl = chromaPanel.newKeyControl("Spill Suppression", "%.0f%%", 0, 100, 5, func(settings *backend.KeySettings, value float64) {
+		settings.SpillSuppression = value / 100
+	})
+
+	chromaPanel.PresetControl = NewKeyPresetControl(&chromaPanel)
+	if settings, ok := chromaPanel.PresetControl.appDefault(); ok && backend.Backend.Name == "" {
+		backend.Backend.UpdateSettings(func(projectSettings *backend.ProjectSettings) {
+			projectSettings.Key = settings
+		})
+	}
+
+	chromaToggleGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Apply Chroma Key Filter"), chromaPanel.ChromaFilterToggle)
+	pickerToggleGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Color Picker Mode"), chromaPanel.ColorPickerToggle)
+	calibrateGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), calibrateButton,
+		widget.NewLabel("or click the live view, or drag over it, in color picker mode"))
+	matteToggleGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Show Matte"), chromaPanel.MatteViewToggle)
+	colorGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		chromaPanel.RedControl.Label, chromaPanel.RedControl.Slider,
+		chromaPanel.GreenControl.Label, chromaPanel.GreenControl.Slider,
+		chromaPanel.BlueControl.Label, chromaPanel.BlueControl.Slider)
+	toleranceGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		chromaPanel.HueControl.Label, chromaPanel.HueControl.Slider,
+		chromaPanel.SaturationControl.Label, chromaPanel.SaturationControl.Slider,
+		chromaPanel.ValueControl.Label, chromaPanel.ValueControl.Slider,
+		chromaPanel.SoftnessControl.Label, chromaPanel.SoftnessControl.Slider,
+		chromaPanel.ChokeControl.Label, chromaPanel.ChokeControl.Slider,
+		chromaPanel.SpillControl.Label, chromaPanel.SpillControl.Slider)
+	chromaPanel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), chromaToggleGroup, pickerToggleGroup, calibrateGroup, matteToggleGroup,
+		chromaPanel.PresetControl.Container, colorGroup, chromaPanel.PreviewColor, toleranceGroup)
+
+	chromaPanel.Refresh()
+
+	return &chromaPanel
+}
@@ -109,19 +109,10 @@ func (s *Gallery) RegenerateThumbnails() {
 	}
 }
 
-func sliceContainsItem(slice []string, item string) bool {
-	for _, test := range slice {
-		if test == item {
-			return true
-		}
-	}
-	return false
-}
-
 //FIXME; sortBySecondCanvasObject
 
 func (s *Gallery) Add(fileName string) {
-	if sliceContainsItem(s.ItemNames, fileName) {
+	if util.ContainsString(s.ItemNames, fileName) {
 		log.Printf("%s already exists", fileName)
 		return
 	}
@@ -268,13 +259,13 @@ func NewGallery(parentContainer *fyne.Container, galleryType GalleryType, baseDi
 		},
 		OnSubmit: func() {
 			log.Printf("creating new project %s", projectEntry.Text)
+			FlushProjectSettings() // into the project open until now
 			backend.Backend.Name = projectEntry.Text
-			backend.Backend.RemoveAll()
+			newProjectTapHandler(projectEntry.Text)
 			err := backend.Backend.Save()
 			if err != nil {
 				log.Printf("there was an error saving creating project %s: %s", projectEntry.Text, err.Error())
 			}
-			newProjectTapHandler(projectEntry.Text)
 			galleryContainer.Add(projectEntry.Text)
 			galleryContainer.ActivateThumbnailView()
 
@@ -334,6 +325,7 @@ func (r *HotImage) TappedSecondary(ev *fyne.PointEvent) {
 
 func NewHotImageFromFile(fileName string, forceResize bool, width int, height int, onTap func(string, *fyne.PointEvent), onSecondaryTap func(string, *fyne.PointEvent)) *HotImage {
 	canvasImage := canvas.NewImageFromFile(fileName)
+	canvasImage.FillMode = canvas.ImageFillContain // thumbnails may have a different aspect ratio than the film strip
 	return NewHotImageFromCanvasImage(canvasImage, forceResize, width, height, onTap, onSecondaryTap)
 }
 
@@ -352,6 +344,34 @@ func NewHotImageFromCanvasImage(canvasImage *canvas.Image, forceResize bool, wid
 	return r
 }
 
+// DraggableHotImage is a HotImage that also reports mouse drags, to select areas of the image
+type DraggableHotImage struct {
+	HotImage
+
+	OnDrag    func(ev *fyne.DragEvent)
+	OnDragEnd func()
+}
+
+func (r *DraggableHotImage) Dragged(ev *fyne.DragEvent) {
+	if r.OnDrag != nil {
+		r.OnDrag(ev)
+	}
+}
+
+func (r *DraggableHotImage) DragEnd() {
+	if r.OnDragEnd != nil {
+		r.OnDragEnd()
+	}
+}
+
+func NewDraggableHotImageFromCanvasImage(canvasImage *canvas.Image, width int, height int, onTap func(string, *fyne.PointEvent), onDrag func(*fyne.DragEvent), onDragEnd func()) *DraggableHotImage {
+	size := fyne.NewSize(width, height)
+	canvasImage.Resize(size)
+	r := &DraggableHotImage{HotImage: HotImage{image: canvasImage, min: size, OnTap: onTap}, OnDrag: onDrag, OnDragEnd: onDragEnd}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
 type HotImageWidgetRenderer struct {
 	hotImage *HotImage
 }
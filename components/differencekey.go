@@ -0,0 +1,207 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"sync"
+
+	"../backend"
+)
+
+// the clean plate is the median of this many frames, so sensor noise doesn't key as subject
+const cleanPlateFrames = 8
+
+// DifferenceKeyPanel keys out the parts of the frame that look like the clean plate, an empty shot of the set
+type DifferenceKeyPanel struct {
+	Container *fyne.Container
+
+	KeyToggle        *widget.Check
+	MatteViewToggle  *widget.Check
+	PlateLabel       *widget.Label
+	ThresholdControl *SliderControl
+	SoftnessControl  *SliderControl
+	CleanupControl   *SliderControl
+
+	// shows which parts differ from the clean plate, to tune the threshold until shadows and noise drop out
+	MatteView bool
+
+	// the plate is replaced from the UI while the capture loop keys against it
+	lock     sync.Mutex
+	plate    gocv.Mat
+	hasPlate bool
+	// the zoomed region of the plate at live view size, rebuilt when either changes
+	previewPlate     gocv.Mat
+	previewPlateRect image.Rectangle
+	previewPlateSize image.Point
+}
+
+// Refresh shows the project's difference key settings and whether a clean plate is loaded
+func (p *DifferenceKeyPanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().DifferenceKey
+	p.ThresholdControl.SetValue(settings.Threshold)
+	p.SoftnessControl.SetValue(settings.Softness)
+	p.CleanupControl.SetValue(settings.Cleanup)
+	p.refreshPlateLabel()
+}
+
+func (p *DifferenceKeyPanel) refreshPlateLabel() {
+	p.lock.Lock()
+	hasPlate := p.hasPlate
+	p.lock.Unlock()
+	if hasPlate {
+		p.PlateLabel.SetText("Clean plate captured")
+	} else {
+		p.PlateLabel.SetText("No clean plate")
+	}
+}
+
+// setPlate replaces the clean plate, an empty plate removes it
+func (p *DifferenceKeyPanel) setPlate(plate gocv.Mat) {
+	p.lock.Lock()
+	p.plate.Close()
+	p.plate = plate
+	p.hasPlate = !plate.Empty()
+	p.previewPlateRect = image.Rectangle{}
+	p.previewPlateSize = image.Point{}
+	p.lock.Unlock()
+	p.refreshPlateLabel()
+}
+
+// ApplyProjectSettings loads the project's clean plate, if it has one
+func (p *DifferenceKeyPanel) ApplyProjectSettings() {
+	plateFileName, err := backend.CleanPlatePath(backend.Backend.Name)
+	if err != nil || backend.Backend.Name == "" {
+		p.setPlate(gocv.NewMat())
+	} else {
+		p.setPlate(gocv.IMRead(plateFileName, gocv.IMReadColor)) // empty if the project has no plate
+	}
+	p.Refresh()
+}
+
+// CapturePlate captures the empty set as the clean plate and saves it with the project
+func (p *DifferenceKeyPanel) CapturePlate(component *TopComponent) error {
+	if backend.Backend.Name == "" {
+		return errors.New("create a project first before capturing a clean plate")
+	}
+	plate := gocv.NewMat()
+	err := component.captureStackedFrame(cleanPlateFrames, backend.StackModeMedian, &plate)
+	if err != nil {
+		plate.Close()
+		return err
+	}
+	plateFileName, err := backend.CleanPlatePath(backend.Backend.Name)
+	if err != nil {
+		plate.Close()
+		return err
+	}
+	if !gocv.IMWrite(plateFileName, plate) {
+		plate.Close()
+		return fmt.Errorf("couldn't save the clean plate to %s", plateFileName)
+	}
+	p.setPlate(plate)
+	return nil
+}
+
+// Apply keys frame against the region rect of the clean plate scaled to the frame's size, and reports whether it
+// could. The live view passes its zoomed region, snapshots the whole frame.
+func (p *DifferenceKeyPanel) Apply(frame gocv.Mat, rect image.Rectangle, background gocv.Mat, garbage backend.GarbageMask, matteView bool, dst *gocv.Mat) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.hasPlate {
+		return false
+	}
+	backend.ConformToResolution(&p.plate, backend.CurrentResolution()) // plates shot before a resolution change
+	size := image.Pt(frame.Cols(), frame.Rows())
+	plate := p.plate
+	if rect != image.Rect(0, 0, p.plate.Cols(), p.plate.Rows()) || size != rect.Size() {
+		if rect != p.previewPlateRect || size != p.previewPlateSize {
+			region := p.plate.Region(rect)
+			gocv.Resize(region, &p.previewPlate, size, 0, 0, gocv.InterpolationLinear)
+			region.Close()
+			p.previewPlateRect = rect
+			p.previewPlateSize = size
+		}
+		plate = p.previewPlate
+	}
+	backend.ApplyDifferenceKey(frame, plate, background, backend.Backend.CurrentSettings().DifferenceKey, garbage, matteView, dst)
+	return true
+}
+
+// CleanPlate copies the clean plate at the capture resolution into dst, false if there is none
+func (p *DifferenceKeyPanel) CleanPlate(dst *gocv.Mat) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.hasPlate {
+		return false
+	}
+	backend.ConformToResolution(&p.plate, backend.CurrentResolution()) // plates shot before a resolution change
+	p.plate.CopyTo(dst)
+	return true
+}
+
+// updateSettings changes the project's difference key settings and saves them
+func (p *DifferenceKeyPanel) updateSettings(update func(settings *backend.DifferenceKeySettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.DifferenceKey)
+	})
+	SaveProjectSettings()
+}
+
+func NewDifferenceKeyPanel(component *TopComponent) *DifferenceKeyPanel {
+	panel := DifferenceKeyPanel{
+		PlateLabel:   widget.NewLabel(""),
+		plate:        gocv.NewMat(),
+		previewPlate: gocv.NewMat(),
+	}
+	panel.KeyToggle = widget.NewCheck("", func(flag bool) {
+		if flag {
+			component.uncheckKeyToggles(panel.KeyToggle)
+			component.SetCaptureMode(CaptureModeDifferenceKey)
+		} else {
+			component.SetCaptureMode(CaptureModeNormal)
+		}
+	})
+	panel.MatteViewToggle = widget.NewCheck("", func(flag bool) {
+		panel.MatteView = flag
+	})
+	captureButton := widget.NewButton("Capture Clean Plate", func() {
+		err := panel.CapturePlate(component)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		}
+	})
+	panel.ThresholdControl = NewSliderControl("Threshold", "%.0f", 1, 100, 1, func(value float64) {
+		panel.updateSettings(func(settings *backend.DifferenceKeySettings) {
+			settings.Threshold = value
+		})
+	})
+	panel.SoftnessControl = NewSliderControl("Edge Softness", "%.1f px", 0, 20, 0.5, func(value float64) {
+		panel.updateSettings(func(settings *backend.DifferenceKeySettings) {
+			settings.Softness = value
+		})
+	})
+	panel.CleanupControl = NewSliderControl("Cleanup", "%.0f px", 0, 20, 1, func(value float64) {
+		panel.updateSettings(func(settings *backend.DifferenceKeySettings) {
+			settings.Cleanup = value
+		})
+	})
+
+	plateGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), captureButton, panel.PlateLabel)
+	controlsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Apply Difference Key"), panel.KeyToggle,
+		widget.NewLabel("Show Matte"), panel.MatteViewToggle,
+		panel.ThresholdControl.Label, panel.ThresholdControl.Slider,
+		panel.SoftnessControl.Label, panel.SoftnessControl.Slider,
+		panel.CleanupControl.Label, panel.CleanupControl.Slider)
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), plateGroup, controlsGroup)
+
+	panel.Refresh()
+
+	return &panel
+}
@@ -10,6 +10,7 @@ import (
 
 	"../backend"
 	"../config"
+	"../util"
 )
 
 const (
@@ -31,6 +32,39 @@ type FilmStrip struct {
 	ViewSize   int
 	ViewOffset int
 	Cursor     int // -1 indicates cursor location is unset
+
+	// which camera's frames are shown, for multi-camera projects
+	Track       int
+	TrackSelect *widget.Select
+}
+
+func trackName(track int) string {
+	if track == backend.TrackMain {
+		return "Main Camera"
+	}
+	return cameraName(track)
+}
+
+// SelectTrack switches the film strip and preview to another camera's frames
+func (f *FilmStrip) SelectTrack(track int) {
+	log.Printf("showing track %s", trackName(track))
+	f.Track = track
+	f.ResetCursor()
+	f.SyncToBackend()
+}
+
+func (f *FilmStrip) refreshTrackSelect() {
+	tracks := backend.Backend.Tracks()
+	options := make([]string, 0)
+	for _, track := range tracks {
+		options = append(options, trackName(track))
+	}
+	if !util.ContainsInt(tracks, f.Track) {
+		f.Track = backend.TrackMain
+	}
+	f.TrackSelect.Options = options
+	f.TrackSelect.Selected = trackName(f.Track)
+	f.TrackSelect.Refresh()
 }
 
 func (f *FilmStrip) Left() {
@@ -45,6 +79,14 @@ func (f *FilmStrip) ResetCursor() {
 	f.Cursor = -1
 }
 
+// InsertionIndex is where the next snapshot goes: after the cursor, or at the end when the cursor is unset
+func (f *FilmStrip) InsertionIndex() int {
+	if f.Cursor < 0 || f.Cursor >= len(backend.Backend.Frames) {
+		return len(backend.Backend.Frames)
+	}
+	return f.Cursor + 1
+}
+
 func (f *FilmStrip) Right() {
 	maxAllowedLeftOffset := len(backend.Backend.Frames) - thumbnailCount
 	if maxAllowedLeftOffset < 0 {
@@ -69,7 +111,8 @@ func (f *FilmStrip) ExclusiveSelectFrame(fileName string) {
 	//log.Printf("will look for backend frame with filename %s", fileName)
 	for idx, frame := range backend.Backend.Frames {
 		pinnedIdx := idx
-		if fileName == frame.ThumbnailFilename {
+		_, thumbnailFileName, _ := frame.Track(f.Track)
+		if fileName == thumbnailFileName {
 			log.Printf("set cursor to backend frame %d, fileName: %s", idx, fileName)
 			f.Cursor = pinnedIdx
 			break
@@ -89,8 +132,9 @@ func (f *FilmStrip) ExclusiveSelectFrame(fileName string) {
 	}
 
 	if f.Cursor >= 0 {
-		fileName := backend.Backend.Frames[f.Cursor].Filename
+		fileName, _, _ := backend.Backend.Frames[f.Cursor].Track(f.Track)
 		AnimationBottomComponent.PreviewImage = canvas.NewImageFromFile(fileName)
+		AnimationBottomComponent.PreviewImage.FillMode = canvas.ImageFillContain
 		AnimationBottomComponent.PreviewImageContainer.Objects[0] = AnimationBottomComponent.PreviewImage
 		AnimationBottomComponent.PreviewImage.SetMinSize(fyne.NewSize(config.WebcamDisplayWidth, config.WebcamDisplayHeight))
 		AnimationBottomComponent.PreviewImageContainer.Refresh()
@@ -105,10 +149,12 @@ func (f *FilmStrip) ExclusiveSelectFrame(fileName string) {
 func (f *FilmStrip) DeleteFrame(fileName string) {
 	for idx, frame := range backend.Backend.Frames {
 		pinnedIdx := idx
-		if fileName == frame.ThumbnailFilename {
+		_, thumbnailFileName, _ := frame.Track(f.Track)
+		if fileName == thumbnailFileName {
 			log.Printf("set cursor to backend frame %d, fileName: %s", idx, fileName)
 			f.Cursor = pinnedIdx
 			backend.Backend.RemoveAt(f.Cursor)
+			AnimationTopComponent.ZoomPanel.refreshKeyframes() // the camera move's keyframes after it moved back
 			break
 		}
 	}
@@ -116,6 +162,7 @@ func (f *FilmStrip) DeleteFrame(fileName string) {
 
 func (f *FilmStrip) SyncToBackend() {
 	log.Printf("syncing with backend")
+	f.refreshTrackSelect()
 	leftIndex := f.ViewOffset
 	rightIndex := len(backend.Backend.Frames) - 1
 	calculatedSize := f.ViewOffset + f.ViewSize
@@ -126,8 +173,16 @@ func (f *FilmStrip) SyncToBackend() {
 	if rightIndex > 0 {
 		//log.Printf("will load visible frames from backend frames")
 		for idx, frame := range backend.Backend.Frames[leftIndex:rightIndex] {
-			pinnedFileName := frame.ThumbnailFilename
-			pinnedThumbnailName := frame.ThumbnailFilename
+			_, thumbnailFileName, ok := frame.Track(f.Track)
+			if !ok {
+				// shot before this camera was added
+				rect := canvas.NewRectangle(color.Gray{Y: 128})
+				rect.SetMinSize(fyne.NewSize(thumbnailWidth, thumbnailHeight))
+				f.VisibleFrames[idx] = rect
+				continue
+			}
+			pinnedFileName := thumbnailFileName
+			pinnedThumbnailName := thumbnailFileName
 			image := NewHotImageFromFile(pinnedThumbnailName, false, thumbnailWidth, thumbnailHeight,
 				func(fileName string, event *fyne.PointEvent) {
 					f.ExclusiveSelectFrame(pinnedFileName)
@@ -180,8 +235,19 @@ func NewFilmStripComponent() *FilmStrip {
 	filmstrip := FilmStrip{
 		VisibleFrames: frames,
 		ViewSize:      thumbnailCount,
+		Track:         backend.TrackMain,
 	}
 
+	filmstrip.TrackSelect = widget.NewSelect([]string{trackName(backend.TrackMain)}, func(choice string) {
+		for _, track := range backend.Backend.Tracks() {
+			if trackName(track) == choice && track != filmstrip.Track {
+				filmstrip.SelectTrack(track)
+				return
+			}
+		}
+	})
+	filmstrip.TrackSelect.Selected = trackName(backend.TrackMain)
+
 	leftButton := widget.NewButton("<", func() {
 		filmstrip.Left()
 		filmstrip.SyncToBackend()
@@ -196,10 +262,11 @@ func NewFilmStripComponent() *FilmStrip {
 	filmstrip.FrameContainer = frameContainer
 	rootLayout := layout.NewHBoxLayout()
 	items := make([]fyne.CanvasObject, 0)
+	items = append(items, filmstrip.TrackSelect)
 	items = append(items, leftButton)
 	items = append(items, frameContainer)
 	items = append(items, rightButton)
-	rootLayout.Layout(items, fyne.NewSize(config.WebcamCaptureWidth, config.WebcamDisplayHeight))
+	rootLayout.Layout(items, fyne.NewSize(config.WindowWidth, config.WebcamDisplayHeight))
 	rootContainer := fyne.NewContainerWithLayout(rootLayout, items...)
 	filmstrip.Container = rootContainer
 
@@ -0,0 +1,136 @@
+package components
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+
+	"../backend"
+)
+
+// FilterPanel edits the project's filter chain. Filters run top to bottom over the live view and snapshots.
+type FilterPanel struct {
+	Container *fyne.Container
+
+	AddSelect *widget.Select
+	// one group of controls per filter in the chain
+	ChainContainer *fyne.Container
+}
+
+// Refresh rebuilds the filter controls from the project settings
+func (p *FilterPanel) Refresh() {
+	chain := backend.Backend.CurrentSettings().Filters
+	objects := make([]fyne.CanvasObject, 0)
+	for idx, settings := range chain {
+		objects = append(objects, p.newFilterControls(idx, settings, len(chain)))
+	}
+	if len(objects) == 0 {
+		objects = append(objects, widget.NewLabel("No filters. Add one above."))
+	}
+	p.ChainContainer.Objects = objects
+	p.ChainContainer.Refresh()
+}
+
+// filterParamFormat shows fractional parameters with two decimals and whole ones without
+func filterParamFormat(param backend.FilterParam) string {
+	if param.Step < 1 {
+		return "%.2f"
+	}
+	return "%.0f"
+}
+
+// newFilterControls returns the enable check, ordering buttons and parameter sliders of the filter at idx
+func (p *FilterPanel) newFilterControls(idx int, settings backend.FilterSettings, count int) fyne.CanvasObject {
+	enableToggle := widget.NewCheck(string(settings.Type), func(flag bool) {
+		p.updateFilters(func(chain []backend.FilterSettings) []backend.FilterSettings {
+			chain[idx].Enabled = flag
+			return chain
+		})
+	})
+	enableToggle.Checked = settings.Enabled
+	upButton := widget.NewButton("Up", func() {
+		p.moveFilter(idx, idx-1)
+	})
+	if idx == 0 {
+		upButton.Disable()
+	}
+	downButton := widget.NewButton("Down", func() {
+		p.moveFilter(idx, idx+1)
+	})
+	if idx == count-1 {
+		downButton.Disable()
+	}
+	removeButton := widget.NewButton("Remove", func() {
+		p.updateFilters(func(chain []backend.FilterSettings) []backend.FilterSettings {
+			return append(chain[:idx], chain[idx+1:]...)
+		})
+		p.Refresh()
+	})
+	headerGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), enableToggle, layout.NewSpacer(), upButton, downButton, removeButton)
+
+	paramsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout())
+	for _, param := range backend.FilterParams(settings.Type) {
+		pinnedParam := param
+		control := NewSliderControl(param.Name, filterParamFormat(param), param.Min, param.Max, param.Step, func(value float64) {
+			p.updateFilters(func(chain []backend.FilterSettings) []backend.FilterSettings {
+				chain[idx] = chain[idx].WithParam(pinnedParam.Name, value)
+				return chain
+			})
+		})
+		control.SetValue(settings.Param(param.Name))
+		paramsGroup.AddObject(control.Label)
+		paramsGroup.AddObject(control.Slider)
+	}
+	return fyne.NewContainerWithLayout(layout.NewVBoxLayout(), headerGroup, paramsGroup)
+}
+
+func (p *FilterPanel) moveFilter(from int, to int) {
+	p.updateFilters(func(chain []backend.FilterSettings) []backend.FilterSettings {
+		if to < 0 || to >= len(chain) {
+			return chain
+		}
+		chain[from], chain[to] = chain[to], chain[from]
+		return chain
+	})
+	p.Refresh()
+}
+
+// updateFilters changes a copy of the project's filter chain, swaps it in under the settings lock and saves it. A
+// frame the capture loop is filtering finishes with the old chain.
+func (p *FilterPanel) updateFilters(update func(chain []backend.FilterSettings) []backend.FilterSettings) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		settings.Filters = update(append([]backend.FilterSettings{}, settings.Filters...))
+	})
+	SaveProjectSettings()
+}
+
+func NewFilterPanel() *FilterPanel {
+	panel := FilterPanel{
+		ChainContainer: fyne.NewContainerWithLayout(layout.NewVBoxLayout()),
+	}
+	filterNames := make([]string, 0)
+	for _, filterType := range backend.FilterTypes {
+		filterNames = append(filterNames, string(filterType))
+	}
+	panel.AddSelect = widget.NewSelect(filterNames, nil)
+	panel.AddSelect.PlaceHolder = "(select filter)"
+	addButton := widget.NewButton("Add Filter", func() {
+		if panel.AddSelect.Selected == "" {
+			DisplayUserTip("Select the filter to add first.")
+			return
+		}
+		panel.updateFilters(func(chain []backend.FilterSettings) []backend.FilterSettings {
+			return append(chain, backend.NewFilterSettings(backend.FilterType(panel.AddSelect.Selected)))
+		})
+		panel.Refresh()
+	})
+
+	addGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), panel.AddSelect, addButton)
+	chainScroll := widget.NewVScrollContainer(panel.ChainContainer)
+	chainScroll.SetMinSize(fyne.NewSize(0, 400))
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), addGroup, chainScroll)
+
+	panel.Refresh()
+
+	return &panel
+}
@@ -0,0 +1,178 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+	"log"
+	"sync"
+	"time"
+
+	"../backend"
+)
+
+// holding this key flips the live view to the captured frame
+const flipKey = fyne.KeyF
+
+// how many times a second auto flip alternates between the live view and the captured frame
+var flipRates = []float64{1, 2, 3, 4, 6}
+
+const defaultFlipRate = 2
+
+// FlipControl swaps the live view for the frame at FilmStrip.Cursor, or the last frame, so animators can
+// compare the next increment against it. The frame is shown while the flip key is held, while toggled on,
+// or every other period in auto mode.
+type FlipControl struct {
+	Container  *fyne.Container
+	FlipButton *widget.Button
+	AutoToggle *widget.Check
+	RateSelect *widget.Select
+
+	lock      sync.Mutex
+	held      bool
+	toggled   bool
+	auto      bool
+	rate      float64
+	autoStart time.Time
+
+	// the flipped-to frame scaled to the live view, only touched by the capture loop
+	frame         gocv.Mat
+	frameFileName string
+	frameSize     image.Point
+	frameVersion  int
+}
+
+func (f *FlipControl) Hold(held bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.held = held
+}
+
+func (f *FlipControl) Toggle() {
+	f.lock.Lock()
+	f.toggled = !f.toggled
+	toggled := f.toggled
+	f.lock.Unlock()
+	if toggled {
+		f.FlipButton.SetText("Show Live")
+	} else {
+		f.FlipButton.SetText("Show Frame")
+	}
+}
+
+func (f *FlipControl) SetAuto(auto bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.auto = auto
+	f.autoStart = time.Now()
+}
+
+func (f *FlipControl) SetRate(rate float64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.rate = rate
+	f.autoStart = time.Now()
+}
+
+// showingFrame reports whether the captured frame should replace the live view right now
+func (f *FlipControl) showingFrame(now time.Time) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.held || f.toggled {
+		return true
+	}
+	if !f.auto {
+		return false
+	}
+	period := int(now.Sub(f.autoStart).Seconds() * f.rate)
+	return period%2 == 1
+}
+
+// flipFrame returns the index of the frame to flip to among frames: the one at the cursor, or the last one
+func flipFrame(frames []*backend.Frame) int {
+	if AnimationFilmStripComponent.Cursor >= 0 && AnimationFilmStripComponent.Cursor < len(frames) {
+		return AnimationFilmStripComponent.Cursor
+	}
+	return len(frames) - 1
+}
+
+// Apply replaces the live view frame dst with the captured frame when flipped, and reports whether it did
+func (f *FlipControl) Apply(dst *gocv.Mat, size image.Point) bool {
+	if !f.showingFrame(time.Now()) {
+		return false
+	}
+	// this runs on the capture loop, so the frames are read from one copy rather than the list the UI changes
+	frames := backend.Backend.FrameList()
+	frameIdx := flipFrame(frames)
+	if frameIdx < 0 {
+		return false
+	}
+	fileName := frames[frameIdx].Filename
+	version := currentFrameImagesVersion()
+	if fileName != f.frameFileName || size != f.frameSize || version != f.frameVersion {
+		if f.frameFileName != "" {
+			f.frame.Close()
+			f.frameFileName = ""
+		}
+		frame, ok := loadPreviewFrame(fileName, size)
+		if !ok {
+			return false
+		}
+		f.frame = frame
+		f.frameFileName = fileName
+		f.frameSize = size
+		f.frameVersion = version
+	}
+	f.frame.CopyTo(dst)
+	gocv.PutText(dst, fmt.Sprintf("FRAME %d", frameIdx+1), image.Pt(8, 24), gocv.FontHersheySimplex, 0.6, color.RGBA{R: 255, G: 255, B: 255}, 2)
+	return true
+}
+
+// OnKeyDown and OnKeyUp implement hold to flip. The key is ignored while a widget has the keyboard focus, so typing
+// into an entry doesn't flip the live view.
+func (f *FlipControl) OnKeyDown(event *fyne.KeyEvent) {
+	if event.Name == flipKey && fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Focused() == nil {
+		f.Hold(true)
+	}
+}
+
+func (f *FlipControl) OnKeyUp(event *fyne.KeyEvent) {
+	if event.Name == flipKey {
+		f.Hold(false)
+	}
+}
+
+func NewFlipControl() *FlipControl {
+	flip := FlipControl{rate: defaultFlipRate}
+	flip.FlipButton = widget.NewButton("Show Frame", func() {
+		if len(backend.Backend.Frames) == 0 {
+			DisplayUserTip("Take a snapshot first to flip between it and the live view.")
+			return
+		}
+		flip.Toggle()
+	})
+	flip.AutoToggle = widget.NewCheck("Auto Flip", func(flag bool) {
+		log.Printf("auto flip=%t", flag)
+		flip.SetAuto(flag)
+	})
+	rateNames := make([]string, 0)
+	for _, rate := range flipRates {
+		rateNames = append(rateNames, fmt.Sprintf("%.0f/s", rate))
+	}
+	flip.RateSelect = widget.NewSelect(rateNames, func(choice string) {
+		for idx, rateName := range rateNames {
+			if rateName == choice {
+				flip.SetRate(flipRates[idx])
+			}
+		}
+	})
+	flip.RateSelect.Selected = fmt.Sprintf("%.0f/s", float64(defaultFlipRate))
+
+	flip.Container = fyne.NewContainerWithLayout(layout.NewHBoxLayout(), flip.FlipButton, flip.AutoToggle, flip.RateSelect,
+		widget.NewLabel(fmt.Sprintf("(hold %s to flip)", flipKey)))
+	return &flip
+}
@@ -0,0 +1,341 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+	"math"
+
+	"../backend"
+	"../config"
+)
+
+// how close to a vertex, in live view pixels, a click has to be to pick it up
+const garbageGrabRadius = 8
+
+var (
+	garbageBackgroundColor = color.RGBA{R: 255, G: 60, B: 60}
+	garbageForegroundColor = color.RGBA{R: 60, G: 255, B: 60}
+	garbageDisabledColor   = color.RGBA{R: 128, G: 128, B: 128}
+)
+
+// GarbageMattePanel edits the project's garbage mattes, polygons drawn on the live view that every key treats as
+// always background or always foreground
+type GarbageMattePanel struct {
+	Container *fyne.Container
+
+	ShowOutlinesToggle *widget.Check
+	DoneButton         *widget.Button
+	StatusLabel        *widget.Label
+	// one row of controls per matte
+	MattesContainer *fyne.Container
+
+	// outlines the mattes on the live view, never part of a snapshot
+	ShowOutlines bool
+
+	component *TopComponent
+	// the matte live view clicks edit, -1 when not editing
+	editing int
+	// the vertex of the edited matte picked up to be moved, -1 when none is
+	grabbed int
+}
+
+// garbageMask is the project's garbage mattes for a key matte that shows region of a frameSize captured frame
+func garbageMask(region image.Rectangle, frameSize image.Point) backend.GarbageMask {
+	return backend.GarbageMask{Mattes: backend.Backend.CurrentSettings().GarbageMattes, Region: region, FrameSize: frameSize}
+}
+
+// Refresh rebuilds the matte controls from the project settings
+func (p *GarbageMattePanel) Refresh() {
+	mattes := backend.Backend.CurrentSettings().GarbageMattes
+	if p.editing >= len(mattes) {
+		p.StopEditing()
+	}
+	objects := make([]fyne.CanvasObject, 0)
+	for idx, matte := range mattes {
+		objects = append(objects, p.newMatteControls(idx, matte))
+	}
+	if len(objects) == 0 {
+		objects = append(objects, widget.NewLabel("No garbage mattes. Add one above."))
+	}
+	p.MattesContainer.Objects = objects
+	p.MattesContainer.Refresh()
+	p.ShowOutlinesToggle.Checked = p.ShowOutlines
+	p.ShowOutlinesToggle.Refresh()
+	if p.editing < 0 {
+		p.DoneButton.Disable()
+		p.StatusLabel.SetText("")
+	} else {
+		p.DoneButton.Enable()
+		p.StatusLabel.SetText(fmt.Sprintf("Editing matte %d. Click: add or move a point. Right-click: delete it.", p.editing+1))
+	}
+}
+
+// newMatteControls returns the enable check, mode select and buttons of the matte at idx
+func (p *GarbageMattePanel) newMatteControls(idx int, matte backend.GarbageMatte) fyne.CanvasObject {
+	enableToggle := widget.NewCheck(fmt.Sprintf("Matte %d (%d points)", idx+1, len(matte.Points)), func(flag bool) {
+		p.updateMattes(func(mattes []backend.GarbageMatte) []backend.GarbageMatte {
+			mattes[idx].Enabled = flag
+			return mattes
+		})
+	})
+	enableToggle.Checked = matte.Enabled
+	modeNames := make([]string, 0)
+	for _, mode := range backend.GarbageMatteModes {
+		modeNames = append(modeNames, mode.String())
+	}
+	modeSelect := widget.NewSelect(modeNames, nil)
+	modeSelect.Selected = matte.Mode.String()
+	modeSelect.OnChanged = func(choice string) {
+		for _, mode := range backend.GarbageMatteModes {
+			if mode.String() == choice {
+				p.updateMattes(func(mattes []backend.GarbageMatte) []backend.GarbageMatte {
+					mattes[idx].Mode = mode
+					return mattes
+				})
+			}
+		}
+	}
+	editButton := widget.NewButton("Edit", func() {
+		p.StartEditing(idx)
+	})
+	if idx == p.editing {
+		editButton.Disable()
+	}
+	removeButton := widget.NewButton("Remove", func() {
+		if p.editing == idx {
+			p.StopEditing()
+		} else if p.editing > idx {
+			p.editing--
+		}
+		p.updateMattes(func(mattes []backend.GarbageMatte) []backend.GarbageMatte {
+			return append(mattes[:idx], mattes[idx+1:]...)
+		})
+		p.Refresh()
+	})
+	return fyne.NewContainerWithLayout(layout.NewHBoxLayout(), enableToggle, layout.NewSpacer(), modeSelect, editButton, removeButton)
+}
+
+// addMatte adds an empty matte and starts drawing it on the live view
+func (p *GarbageMattePanel) addMatte(mode backend.GarbageMatteMode) {
+	p.updateMattes(func(mattes []backend.GarbageMatte) []backend.GarbageMatte {
+		return append(mattes, backend.GarbageMatte{Enabled: true, Mode: mode})
+	})
+	p.StartEditing(len(backend.Backend.CurrentSettings().GarbageMattes) - 1)
+}
+
+// StartEditing turns the live view into a hot image whose clicks edit the vertices of the matte at idx
+func (p *GarbageMattePanel) StartEditing(idx int) {
+	if p.component.ChromaPanel.ColorPickerToggle.Checked {
+		p.component.ChromaPanel.ColorPickerToggle.SetChecked(false) // both pick points on the live view
+	}
+	p.component.ZoomPanel.StopDragging()
+	p.editing = idx
+	p.grabbed = -1
+	hotImage := NewHotImageFromCanvasImage(p.component.WebcamImage, true, config.WebcamDisplayWidth, config.WebcamDisplayHeight,
+		func(s string, event *fyne.PointEvent) {
+			p.editPoint(displayToPreview(event.Position.X, event.Position.Y))
+		}, func(s string, event *fyne.PointEvent) {
+			p.deletePoint(displayToPreview(event.Position.X, event.Position.Y))
+		})
+	p.component.WebcamImageContainer.Objects[0] = hotImage
+	p.component.WebcamImageContainer.Refresh()
+	p.Refresh()
+}
+
+// StopEditing gives the live view back its plain image
+func (p *GarbageMattePanel) StopEditing() {
+	if p.editing < 0 {
+		return
+	}
+	p.editing = -1
+	p.grabbed = -1
+	p.component.WebcamImageContainer.Objects[0] = p.component.WebcamImage
+	p.component.WebcamImageContainer.Refresh()
+	p.Refresh()
+}
+
+// ApplyProjectSettings stops editing the previous project's mattes and shows the new project's
+func (p *GarbageMattePanel) ApplyProjectSettings() {
+	p.StopEditing()
+	p.Refresh()
+}
+
+// liveViewRegion is the region of the captured frame the live view shows, and the frame's size
+func (p *GarbageMattePanel) liveViewRegion() (image.Rectangle, image.Point) {
+	resolution := backend.CurrentResolution()
+	return p.component.zoomRect(resolution.Width, resolution.Height), image.Pt(resolution.Width, resolution.Height)
+}
+
+// nearestVertex returns the vertex of the edited matte within grab distance of the live view pixel pt, or -1
+func (p *GarbageMattePanel) nearestVertex(matte backend.GarbageMatte, pt image.Point) int {
+	region, frameSize := p.liveViewRegion()
+	nearest, nearestDistance := -1, float64(garbageGrabRadius)
+	for idx, vertex := range matte.ImagePoints(frameSize, region, previewSize()) {
+		distance := math.Hypot(float64(vertex.X-pt.X), float64(vertex.Y-pt.Y))
+		if distance <= nearestDistance {
+			nearest, nearestDistance = idx, distance
+		}
+	}
+	return nearest
+}
+
+// editPoint handles a click on the live view pixel pt: it drops a picked up vertex there, picks up the vertex under
+// it, or adds a vertex on the nearest edge
+func (p *GarbageMattePanel) editPoint(pt image.Point) {
+	mattes := backend.Backend.CurrentSettings().GarbageMattes
+	if p.editing < 0 || p.editing >= len(mattes) {
+		return
+	}
+	region, frameSize := p.liveViewRegion()
+	point := backend.FramePointFromImage(pt, frameSize, region, previewSize())
+	editing, grabbed := p.editing, p.grabbed
+	if grabbed >= 0 {
+		p.updateMattes(func(mattes []backend.GarbageMatte) []backend.GarbageMatte {
+			mattes[editing].Points[grabbed] = point
+			return mattes
+		})
+		p.grabbed = -1
+		return
+	}
+	if vertex := p.nearestVertex(mattes[editing], pt); vertex >= 0 {
+		p.grabbed = vertex
+		return
+	}
+	insertAt := nearestEdge(mattes[editing].ImagePoints(frameSize, region, previewSize()), pt)
+	p.updateMattes(func(mattes []backend.GarbageMatte) []backend.GarbageMatte {
+		points := append(mattes[editing].Points[:insertAt], append([]backend.FramePoint{point}, mattes[editing].Points[insertAt:]...)...)
+		mattes[editing].Points = points
+		return mattes
+	})
+	p.Refresh()
+}
+
+// deletePoint removes the vertex of the edited matte under the live view pixel pt, if there is one
+func (p *GarbageMattePanel) deletePoint(pt image.Point) {
+	mattes := backend.Backend.CurrentSettings().GarbageMattes
+	if p.editing < 0 || p.editing >= len(mattes) {
+		return
+	}
+	editing := p.editing
+	vertex := p.nearestVertex(mattes[editing], pt)
+	if vertex < 0 {
+		return
+	}
+	p.grabbed = -1
+	p.updateMattes(func(mattes []backend.GarbageMatte) []backend.GarbageMatte {
+		mattes[editing].Points = append(mattes[editing].Points[:vertex], mattes[editing].Points[vertex+1:]...)
+		return mattes
+	})
+	p.Refresh()
+}
+
+// nearestEdge returns where a vertex at pt goes in a polygon: after the start of the edge closest to it, or at the end
+// while the polygon has fewer than three vertices
+func nearestEdge(points []image.Point, pt image.Point) int {
+	if len(points) < 3 {
+		return len(points)
+	}
+	insertAt, nearestDistance := len(points), math.Inf(1)
+	for idx := range points {
+		distance := segmentDistance(points[idx], points[(idx+1)%len(points)], pt)
+		if distance < nearestDistance {
+			insertAt, nearestDistance = idx+1, distance
+		}
+	}
+	return insertAt
+}
+
+// segmentDistance is the distance from pt to the line segment from a to b
+func segmentDistance(a image.Point, b image.Point, pt image.Point) float64 {
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, (float64(pt.X-a.X)*dx+float64(pt.Y-a.Y)*dy)/length))
+	}
+	return math.Hypot(float64(a.X)+t*dx-float64(pt.X), float64(a.Y)+t*dy-float64(pt.Y))
+}
+
+// Apply outlines the garbage mattes over the live view frame dst while editing or when outlines are shown
+func (p *GarbageMattePanel) Apply(dst *gocv.Mat, garbage backend.GarbageMask) {
+	editing, grabbed := p.editing, p.grabbed
+	if !p.ShowOutlines && editing < 0 {
+		return
+	}
+	size := image.Pt(dst.Cols(), dst.Rows())
+	for idx, matte := range garbage.Mattes {
+		outlineColor := garbageBackgroundColor
+		if !matte.Enabled {
+			outlineColor = garbageDisabledColor
+		} else if matte.Mode == backend.GarbageMatteForeground {
+			outlineColor = garbageForegroundColor
+		}
+		points := matte.ImagePoints(garbage.FrameSize, garbage.Region, size)
+		for vertex := range points {
+			if vertex+1 < len(points) || len(points) >= 3 {
+				gocv.Line(dst, points[vertex], points[(vertex+1)%len(points)], outlineColor, 1)
+			}
+		}
+		if idx != editing {
+			continue
+		}
+		for vertex, point := range points {
+			thickness := 1
+			if vertex == grabbed {
+				thickness = -1 // filled
+			}
+			gocv.Circle(dst, point, 4, outlineColor, thickness)
+		}
+	}
+}
+
+// updateMattes changes a deep copy of the project's garbage mattes, swaps it in under the settings lock and saves it.
+// A mask the capture loop is keying with keeps the mattes it was made with.
+func (p *GarbageMattePanel) updateMattes(update func(mattes []backend.GarbageMatte) []backend.GarbageMatte) {
+	current := backend.Backend.CurrentSettings().GarbageMattes
+	mattes := make([]backend.GarbageMatte, 0, len(current))
+	for _, matte := range current {
+		mattes = append(mattes, matte.Clone())
+	}
+	mattes = update(mattes)
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		settings.GarbageMattes = mattes
+	})
+	SaveProjectSettings()
+}
+
+func NewGarbageMattePanel(component *TopComponent) *GarbageMattePanel {
+	panel := GarbageMattePanel{
+		StatusLabel:     widget.NewLabel(""),
+		MattesContainer: fyne.NewContainerWithLayout(layout.NewVBoxLayout()),
+		component:       component,
+		editing:         -1,
+		grabbed:         -1,
+	}
+	panel.ShowOutlinesToggle = widget.NewCheck("", func(flag bool) {
+		panel.ShowOutlines = flag
+	})
+	addBackgroundButton := widget.NewButton("Add Background Matte", func() {
+		panel.addMatte(backend.GarbageMatteBackground)
+	})
+	addForegroundButton := widget.NewButton("Add Foreground Matte", func() {
+		panel.addMatte(backend.GarbageMatteForeground)
+	})
+	panel.DoneButton = widget.NewButton("Done Editing", func() {
+		panel.StopEditing()
+	})
+
+	addGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), addBackgroundButton, addForegroundButton, panel.DoneButton)
+	outlinesGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Show Outlines"), panel.ShowOutlinesToggle)
+	mattesScroll := widget.NewVScrollContainer(panel.MattesContainer)
+	mattesScroll.SetMinSize(fyne.NewSize(0, 300))
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), addGroup, outlinesGroup, panel.StatusLabel, mattesScroll)
+
+	panel.Refresh()
+
+	return &panel
+}
@@ -0,0 +1,224 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+
+	"../backend"
+)
+
+const (
+	// share of the frame inside the action and title safe areas
+	actionSafeArea = 0.9
+	titleSafeArea  = 0.8
+
+	// how much of the picture stays visible outside an aspect ratio mask
+	maskBrightness = 0.25
+
+	maxGridSpacing = 400
+)
+
+var (
+	guideColor      = color.RGBA{R: 255, G: 255, B: 255}
+	gridColor       = color.RGBA{R: 160, G: 160, B: 160}
+	actionSafeColor = color.RGBA{R: 255, G: 220, B: 0}
+	titleSafeColor  = color.RGBA{R: 255, G: 120, B: 0}
+)
+
+// AspectMask is a framing the live view can be masked to
+type AspectMask struct {
+	Name   string
+	Aspect float64
+}
+
+var AspectMasks = []AspectMask{
+	{"None", 0},
+	{"2.39:1", 2.39},
+	{"1.85:1", 1.85},
+	{"16:9", 16.0 / 9.0},
+	{"4:3", 4.0 / 3.0},
+	{"1:1", 1},
+	{"9:16", 9.0 / 16.0},
+}
+
+func aspectMaskName(aspect float64) string {
+	for _, mask := range AspectMasks {
+		if mask.Aspect == aspect {
+			return mask.Name
+		}
+	}
+	return fmt.Sprintf("%.2f:1", aspect)
+}
+
+type GuidesPanel struct {
+	Container *fyne.Container
+
+	RuleOfThirdsToggle *widget.Check
+	CenterCrossToggle  *widget.Check
+	ActionSafeToggle   *widget.Check
+	TitleSafeToggle    *widget.Check
+	GridControl        *SliderControl
+	MaskSelect         *widget.Select
+}
+
+// Refresh shows which guides the project draws over the live view, its grid spacing and aspect ratio mask
+func (p *GuidesPanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().Guides
+	p.RuleOfThirdsToggle.Checked = settings.RuleOfThirds
+	p.RuleOfThirdsToggle.Refresh()
+	p.CenterCrossToggle.Checked = settings.CenterCross
+	p.CenterCrossToggle.Refresh()
+	p.ActionSafeToggle.Checked = settings.ActionSafe
+	p.ActionSafeToggle.Refresh()
+	p.TitleSafeToggle.Checked = settings.TitleSafe
+	p.TitleSafeToggle.Refresh()
+	p.GridControl.SetValue(float64(settings.GridSpacing))
+	p.MaskSelect.Selected = aspectMaskName(settings.MaskAspect)
+	p.MaskSelect.Refresh()
+}
+
+// Apply draws the enabled guides over the live view frame dst
+func (p *GuidesPanel) Apply(dst *gocv.Mat) {
+	settings := backend.Backend.CurrentSettings().Guides
+	width, height := dst.Cols(), dst.Rows()
+	frame := image.Rect(0, 0, width, height)
+
+	if settings.MaskAspect > 0 {
+		frame = maskToAspect(dst, settings.MaskAspect)
+	}
+	if settings.GridSpacing > 0 {
+		// spacing is in captured pixels, the live view is smaller
+		spacing := float64(settings.GridSpacing) * float64(height) / float64(backend.CurrentResolution().Height)
+		if spacing >= 2 {
+			for x := float64(frame.Min.X) + spacing; x < float64(frame.Max.X); x += spacing {
+				gocv.Line(dst, image.Pt(int(x), frame.Min.Y), image.Pt(int(x), frame.Max.Y), gridColor, 1)
+			}
+			for y := float64(frame.Min.Y) + spacing; y < float64(frame.Max.Y); y += spacing {
+				gocv.Line(dst, image.Pt(frame.Min.X, int(y)), image.Pt(frame.Max.X, int(y)), gridColor, 1)
+			}
+		}
+	}
+	if settings.RuleOfThirds {
+		for third := 1; third <= 2; third++ {
+			x := frame.Min.X + frame.Dx()*third/3
+			y := frame.Min.Y + frame.Dy()*third/3
+			gocv.Line(dst, image.Pt(x, frame.Min.Y), image.Pt(x, frame.Max.Y), guideColor, 1)
+			gocv.Line(dst, image.Pt(frame.Min.X, y), image.Pt(frame.Max.X, y), guideColor, 1)
+		}
+	}
+	if settings.CenterCross {
+		center := image.Pt(frame.Min.X+frame.Dx()/2, frame.Min.Y+frame.Dy()/2)
+		arm := frame.Dy() / 20
+		gocv.Line(dst, center.Sub(image.Pt(arm, 0)), center.Add(image.Pt(arm, 0)), guideColor, 1)
+		gocv.Line(dst, center.Sub(image.Pt(0, arm)), center.Add(image.Pt(0, arm)), guideColor, 1)
+	}
+	if settings.ActionSafe {
+		gocv.Rectangle(dst, insetRect(frame, actionSafeArea), actionSafeColor, 1)
+	}
+	if settings.TitleSafe {
+		gocv.Rectangle(dst, insetRect(frame, titleSafeArea), titleSafeColor, 1)
+	}
+}
+
+// maskToAspect darkens the parts of dst outside the centred region with the given aspect ratio and returns that region
+func maskToAspect(dst *gocv.Mat, aspect float64) image.Rectangle {
+	width, height := dst.Cols(), dst.Rows()
+	frame := image.Rect(0, 0, width, height)
+	bars := make([]image.Rectangle, 0, 2)
+	if float64(width)/float64(height) > aspect { // pillarbox
+		inner := int(float64(height) * aspect)
+		offset := (width - inner) / 2
+		frame = image.Rect(offset, 0, offset+inner, height)
+		bars = append(bars, image.Rect(0, 0, offset, height), image.Rect(offset+inner, 0, width, height))
+	} else { // letterbox
+		inner := int(float64(width) / aspect)
+		offset := (height - inner) / 2
+		frame = image.Rect(0, offset, width, offset+inner)
+		bars = append(bars, image.Rect(0, 0, width, offset), image.Rect(0, offset+inner, width, height))
+	}
+	for _, bar := range bars {
+		if bar.Empty() {
+			continue
+		}
+		region := dst.Region(bar)
+		region.MultiplyFloat(maskBrightness)
+		region.Close()
+	}
+	return frame
+}
+
+// insetRect shrinks rect around its centre to the given share of its width and height
+func insetRect(rect image.Rectangle, share float64) image.Rectangle {
+	xInset := int(float64(rect.Dx()) * (1 - share) / 2)
+	yInset := int(float64(rect.Dy()) * (1 - share) / 2)
+	return image.Rect(rect.Min.X+xInset, rect.Min.Y+yInset, rect.Max.X-xInset, rect.Max.Y-yInset)
+}
+
+// updateSettings changes the project's guide settings and saves them
+func (p *GuidesPanel) updateSettings(update func(settings *backend.GuideSettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.Guides)
+	})
+	SaveProjectSettings()
+}
+
+func NewGuidesPanel() *GuidesPanel {
+	panel := GuidesPanel{}
+	panel.RuleOfThirdsToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.GuideSettings) {
+			settings.RuleOfThirds = flag
+		})
+	})
+	panel.CenterCrossToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.GuideSettings) {
+			settings.CenterCross = flag
+		})
+	})
+	panel.ActionSafeToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.GuideSettings) {
+			settings.ActionSafe = flag
+		})
+	})
+	panel.TitleSafeToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.GuideSettings) {
+			settings.TitleSafe = flag
+		})
+	})
+	panel.GridControl = NewSliderControl("Grid", "%.0f px", 0, maxGridSpacing, 10, func(value float64) {
+		panel.updateSettings(func(settings *backend.GuideSettings) {
+			settings.GridSpacing = int(value)
+		})
+	})
+	panel.GridControl.Zero = "off"
+	maskNames := make([]string, 0)
+	for _, mask := range AspectMasks {
+		maskNames = append(maskNames, mask.Name)
+	}
+	panel.MaskSelect = widget.NewSelect(maskNames, func(choice string) {
+		for _, mask := range AspectMasks {
+			if mask.Name == choice {
+				panel.updateSettings(func(settings *backend.GuideSettings) {
+					settings.MaskAspect = mask.Aspect
+				})
+			}
+		}
+	})
+
+	togglesGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Rule of Thirds"), panel.RuleOfThirdsToggle,
+		widget.NewLabel("Centre Cross"), panel.CenterCrossToggle,
+		widget.NewLabel("Action Safe (90%)"), panel.ActionSafeToggle,
+		widget.NewLabel("Title Safe (80%)"), panel.TitleSafeToggle)
+	gridGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), panel.GridControl.Label, panel.GridControl.Slider)
+	maskGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Aspect Ratio Mask"), panel.MaskSelect)
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), togglesGroup, gridGroup, maskGroup)
+
+	panel.Refresh()
+
+	return &panel
+}
@@ -0,0 +1,254 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"log"
+	"strings"
+
+	"../backend"
+)
+
+// KeyPresetControl saves and loads named chroma key setups, either with the project or shared by all projects
+type KeyPresetControl struct {
+	Container *fyne.Container
+
+	PresetSelect *widget.Select
+	DefaultLabel *widget.Label
+
+	panel   *ChromaPanel
+	library backend.KeyPresetLibrary
+	// the presets behind the select's options
+	entries map[string]keyPresetEntry
+}
+
+type keyPresetEntry struct {
+	preset backend.KeyPreset
+	shared bool
+}
+
+func keyPresetLabel(name string, shared bool) string {
+	if shared {
+		return fmt.Sprintf("%s (shared)", name)
+	}
+	return name
+}
+
+// Refresh syncs the preset list and the defaults with the project settings and the shared library
+func (c *KeyPresetControl) Refresh() {
+	options := make([]string, 0)
+	c.entries = make(map[string]keyPresetEntry)
+	settings := backend.Backend.CurrentSettings()
+	for _, preset := range settings.KeyPresets {
+		label := keyPresetLabel(preset.Name, false)
+		options = append(options, label)
+		c.entries[label] = keyPresetEntry{preset: preset}
+	}
+	for _, preset := range c.library.Presets {
+		label := keyPresetLabel(preset.Name, true)
+		options = append(options, label)
+		c.entries[label] = keyPresetEntry{preset: preset, shared: true}
+	}
+	c.PresetSelect.Options = options
+	if _, ok := c.entries[c.PresetSelect.Selected]; !ok {
+		c.PresetSelect.Selected = ""
+	}
+	c.PresetSelect.Refresh()
+
+	defaults := make([]string, 0)
+	if name := settings.DefaultKeyPreset; name != "" {
+		defaults = append(defaults, fmt.Sprintf("project: %s", name))
+	}
+	if c.library.Default != "" {
+		defaults = append(defaults, fmt.Sprintf("app: %s", c.library.Default))
+	}
+	if len(defaults) == 0 {
+		c.DefaultLabel.SetText("No default presets")
+	} else {
+		c.DefaultLabel.SetText(fmt.Sprintf("Defaults - %s", strings.Join(defaults, ", ")))
+	}
+}
+
+// selected returns the preset chosen in the select, or tells the user to choose one
+func (c *KeyPresetControl) selected() (keyPresetEntry, bool) {
+	entry, ok := c.entries[c.PresetSelect.Selected]
+	if !ok {
+		DisplayUserTip("Select a key preset first.")
+	}
+	return entry, ok
+}
+
+// Load keys with the selected preset
+func (c *KeyPresetControl) Load() {
+	entry, ok := c.selected()
+	if !ok {
+		return
+	}
+	c.panel.updateSettings(func(settings *backend.KeySettings) {
+		*settings = entry.preset.Settings
+	})
+	c.panel.Refresh()
+}
+
+// OpenSaveDialog asks for a name and saves the current key settings under it
+func (c *KeyPresetControl) OpenSaveDialog() {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Preset name")
+	sharedToggle := widget.NewCheck("Share with all projects", nil)
+	if backend.Backend.Name == "" {
+		sharedToggle.Checked = true // there is no project to save it with
+		sharedToggle.Disable()
+	}
+	content := fyne.NewContainerWithLayout(layout.NewVBoxLayout(), nameEntry, sharedToggle)
+	dialog.ShowCustomConfirm("Save Key Preset", "Save", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		name := strings.TrimSpace(nameEntry.Text)
+		if name == "" {
+			DisplayUserTip("Key presets need a name.")
+			return
+		}
+		c.save(backend.KeyPreset{Name: name, Settings: backend.Backend.CurrentSettings().Key}, sharedToggle.Checked)
+		c.PresetSelect.Selected = keyPresetLabel(name, sharedToggle.Checked)
+		c.Refresh()
+	}, fyne.CurrentApp().Driver().AllWindows()[0])
+}
+
+func (c *KeyPresetControl) save(preset backend.KeyPreset, shared bool) {
+	if shared {
+		c.library.Presets = backend.WithKeyPreset(c.library.Presets, preset)
+		c.saveLibrary()
+		return
+	}
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		settings.KeyPresets = backend.WithKeyPreset(settings.KeyPresets, preset)
+	})
+	SaveProjectSettings()
+}
+
+// Delete removes the selected preset, and the defaults that pointed to it
+func (c *KeyPresetControl) Delete() {
+	entry, ok := c.selected()
+	if !ok {
+		return
+	}
+	name := entry.preset.Name
+	if entry.shared {
+		c.library.Presets = backend.WithoutKeyPreset(c.library.Presets, name)
+		if c.library.Default == name {
+			c.library.Default = ""
+		}
+		c.saveLibrary()
+	}
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		if !entry.shared {
+			settings.KeyPresets = backend.WithoutKeyPreset(settings.KeyPresets, name)
+		}
+		if _, ok := settings.DefaultKeyPresetSettings(c.library); !ok {
+			settings.DefaultKeyPreset = ""
+		}
+	})
+	SaveProjectSettings()
+	c.Refresh()
+}
+
+// ToggleProjectDefault makes the selected preset the one the project is keyed with when it is opened, or stops it
+// being that
+func (c *KeyPresetControl) ToggleProjectDefault() {
+	if backend.Backend.Name == "" {
+		DisplayUserTip("Create or open a project first.")
+		return
+	}
+	entry, ok := c.selected()
+	if !ok {
+		return
+	}
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		if settings.DefaultKeyPreset == entry.preset.Name {
+			settings.DefaultKeyPreset = ""
+		} else {
+			settings.DefaultKeyPreset = entry.preset.Name
+		}
+	})
+	SaveProjectSettings()
+	c.Refresh()
+}
+
+// ToggleAppDefault makes the selected shared preset the one the app is keyed with when it starts, or stops it being
+// that
+func (c *KeyPresetControl) ToggleAppDefault() {
+	entry, ok := c.selected()
+	if !ok {
+		return
+	}
+	if !entry.shared {
+		DisplayUserTip("Only shared presets can be the app default. Save the preset again with 'Share with all projects'.")
+		return
+	}
+	if c.library.Default == entry.preset.Name {
+		c.library.Default = ""
+	} else {
+		c.library.Default = entry.preset.Name
+	}
+	c.saveLibrary()
+	c.Refresh()
+}
+
+func (c *KeyPresetControl) saveLibrary() {
+	err := c.library.Save()
+	if err != nil {
+		log.Printf("error saving shared key presets: %s", err.Error())
+		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+	}
+}
+
+// appDefault returns the key settings of the app's default preset
+func (c *KeyPresetControl) appDefault() (backend.KeySettings, bool) {
+	if c.library.Default == "" {
+		return backend.KeySettings{}, false
+	}
+	preset, ok := backend.FindKeyPreset(c.library.Presets, c.library.Default)
+	return preset.Settings, ok
+}
+
+func NewKeyPresetControl(panel *ChromaPanel) *KeyPresetControl {
+	control := KeyPresetControl{
+		DefaultLabel: widget.NewLabel(""),
+		panel:        panel,
+	}
+	library, err := backend.LoadKeyPresetLibrary()
+	if err != nil {
+		log.Printf("error loading shared key presets: %s", err.Error())
+	}
+	control.library = library
+	control.PresetSelect = widget.NewSelect([]string{}, nil)
+	control.PresetSelect.PlaceHolder = "(select preset)"
+	loadButton := widget.NewButton("Load", func() {
+		control.Load()
+	})
+	saveButton := widget.NewButton("Save As...", func() {
+		control.OpenSaveDialog()
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		control.Delete()
+	})
+	projectDefaultButton := widget.NewButton("Project Default", func() {
+		control.ToggleProjectDefault()
+	})
+	appDefaultButton := widget.NewButton("App Default", func() {
+		control.ToggleAppDefault()
+	})
+
+	presetGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), widget.NewLabel("Presets"), control.PresetSelect,
+		loadButton, saveButton, deleteButton)
+	defaultGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), projectDefaultButton, appDefaultButton, control.DefaultLabel)
+	control.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), presetGroup, defaultGroup)
+
+	control.Refresh()
+
+	return &control
+}
@@ -0,0 +1,266 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/storage"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"path/filepath"
+	"sync"
+
+	"../backend"
+)
+
+// LayerPanel edits the project's layer stack: plates composited behind the keyed subject, over the background, and
+// overlays such as props or frames composited in front of it. Layers are drawn in list order, the last one on top.
+type LayerPanel struct {
+	Container *fyne.Container
+
+	SequenceToggle *widget.Check
+	// one group of controls per layer
+	LayersContainer *fyne.Container
+
+	component *TopComponent
+
+	// the opened layers, in the order of the project's layers, reopened while the capture loop draws them
+	lock   sync.Mutex
+	layers backend.LayerStack
+}
+
+// Refresh rebuilds the layer controls from the project settings
+func (p *LayerPanel) Refresh() {
+	layers := backend.Backend.CurrentSettings().Layers
+	objects := make([]fyne.CanvasObject, 0)
+	for idx, settings := range layers {
+		objects = append(objects, p.newLayerControls(idx, settings, len(layers)))
+	}
+	if len(objects) == 0 {
+		objects = append(objects, widget.NewLabel("No layers. Add one above."))
+	}
+	p.LayersContainer.Objects = objects
+	p.LayersContainer.Refresh()
+}
+
+// layerName is the file name of a layer, marked when it couldn't be opened
+func (p *LayerPanel) layerName(idx int, settings backend.LayerSettings) string {
+	name := filepath.Base(settings.Path)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.layers.Missing(idx) {
+		return fmt.Sprintf("%s (missing)", name)
+	}
+	return name
+}
+
+// newLayerControls returns the visibility check, ordering buttons and placement controls of the layer at idx
+func (p *LayerPanel) newLayerControls(idx int, settings backend.LayerSettings, count int) fyne.CanvasObject {
+	visibleToggle := widget.NewCheck(p.layerName(idx, settings), func(flag bool) {
+		p.updateLayers(func(layers []backend.LayerSettings) []backend.LayerSettings {
+			layers[idx].Visible = flag
+			return layers
+		})
+	})
+	visibleToggle.Checked = settings.Visible
+	upButton := widget.NewButton("Up", func() {
+		p.moveLayer(idx, idx-1)
+	})
+	if idx == 0 {
+		upButton.Disable()
+	}
+	downButton := widget.NewButton("Down", func() {
+		p.moveLayer(idx, idx+1)
+	})
+	if idx == count-1 {
+		downButton.Disable()
+	}
+	removeButton := widget.NewButton("Remove", func() {
+		p.updateLayers(func(layers []backend.LayerSettings) []backend.LayerSettings {
+			return append(layers[:idx], layers[idx+1:]...)
+		})
+		p.Refresh()
+	})
+	headerGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), visibleToggle, layout.NewSpacer(), upButton, downButton, removeButton)
+
+	placementNames := make([]string, 0)
+	for _, placement := range backend.LayerPlacements {
+		placementNames = append(placementNames, placement.String())
+	}
+	placementLabel := widget.NewLabel(p.placementLabel(settings.Placement))
+	placementSelect := widget.NewSelect(placementNames, nil)
+	placementSelect.Selected = settings.Placement.String()
+	placementSelect.OnChanged = func(choice string) {
+		for _, placement := range backend.LayerPlacements {
+			if placement.String() == choice {
+				placementLabel.SetText(p.placementLabel(placement))
+				p.updateLayers(func(layers []backend.LayerSettings) []backend.LayerSettings {
+					layers[idx].Placement = placement
+					return layers
+				})
+			}
+		}
+	}
+	loopToggle := widget.NewCheck("", func(flag bool) {
+		p.updateLayers(func(layers []backend.LayerSettings) []backend.LayerSettings {
+			layers[idx].Loop = flag
+			return layers
+		})
+	})
+	loopToggle.Checked = settings.Loop
+
+	paramsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		placementLabel, placementSelect,
+		widget.NewLabel("Loop"), loopToggle)
+	p.addLayerSlider(paramsGroup, idx, "Opacity", 0, 100, settings.Opacity, func(layer *backend.LayerSettings, value float64) {
+		layer.Opacity = value
+	})
+	p.addLayerSlider(paramsGroup, idx, "Scale", 10, 400, settings.Scale, func(layer *backend.LayerSettings, value float64) {
+		layer.Scale = value
+	})
+	p.addLayerSlider(paramsGroup, idx, "X", -100, 100, settings.OffsetX, func(layer *backend.LayerSettings, value float64) {
+		layer.OffsetX = value
+	})
+	p.addLayerSlider(paramsGroup, idx, "Y", -100, 100, settings.OffsetY, func(layer *backend.LayerSettings, value float64) {
+		layer.OffsetY = value
+	})
+	return fyne.NewContainerWithLayout(layout.NewVBoxLayout(), headerGroup, paramsGroup)
+}
+
+// placementLabel names the placement control, warning that layers behind the subject only show through a key.
+// Without one the camera frame covers the whole background.
+func (p *LayerPanel) placementLabel(placement backend.LayerPlacement) string {
+	if placement == backend.LayerBehind && p.component.keyMode() == backend.KeyNone {
+		return "Placement (needs a key)"
+	}
+	return "Placement"
+}
+
+// addLayerSlider adds a percentage slider for a setting of the layer at idx to group
+func (p *LayerPanel) addLayerSlider(group *fyne.Container, idx int, name string, min float64, max float64, value float64,
+	set func(layer *backend.LayerSettings, value float64)) {
+	control := NewSliderControl(name, "%.0f%%", min, max, 5, func(value float64) {
+		p.updateLayers(func(layers []backend.LayerSettings) []backend.LayerSettings {
+			set(&layers[idx], value/100)
+			return layers
+		})
+	})
+	control.SetValue(value * 100)
+	group.AddObject(control.Label)
+	group.AddObject(control.Slider)
+}
+
+func (p *LayerPanel) moveLayer(from int, to int) {
+	p.updateLayers(func(layers []backend.LayerSettings) []backend.LayerSettings {
+		if to < 0 || to >= len(layers) {
+			return layers
+		}
+		layers[from], layers[to] = layers[to], layers[from]
+		return layers
+	})
+	p.Refresh()
+}
+
+// Add opens the layer at path, as the numbered sequence it belongs to if sequence is set, and puts it on top of the
+// stack
+func (p *LayerPanel) Add(path string, sequence bool) error {
+	source, err := backend.OpenLayerSource(path, sequence)
+	if err != nil {
+		return err
+	}
+	source.Close() // only checks the layer opens, the stack opens it again once it is in the settings
+	p.updateLayers(func(layers []backend.LayerSettings) []backend.LayerSettings {
+		return append(layers, backend.NewLayerSettings(path, sequence))
+	})
+	p.Refresh()
+	return nil
+}
+
+// reopen opens the project's layers, keeping the ones already open and closing the ones no longer used
+func (p *LayerPanel) reopen() {
+	p.lock.Lock()
+	p.layers.Open(backend.Backend.CurrentSettings().Layers)
+	p.lock.Unlock()
+}
+
+// ApplyProjectSettings reopens the project's layers
+func (p *LayerPanel) ApplyProjectSettings() {
+	p.reopen()
+	p.Refresh()
+}
+
+func (p *LayerPanel) OpenFileDialog() {
+	win := fyne.CurrentApp().Driver().AllWindows()[0]
+	open := dialog.NewFileOpen(func(read fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		if read == nil {
+			return
+		}
+		defer read.Close()
+		fileName := read.URI().String()[len(read.URI().Scheme())+3:] // remove "file://"
+		err = p.Add(fileName, p.SequenceToggle.Checked)
+		if err != nil {
+			dialog.ShowError(err, win)
+		}
+	}, win)
+
+	extensions := append(append([]string{}, backend.ImageExtensions...), backend.VideoExtensions...)
+	open.SetFilter(storage.NewExtensionFileFilter(extensions))
+	open.Show()
+}
+
+// ApplyBehind composites the visible layers placed behind the subject onto a background for animation frame index
+func (p *LayerPanel) ApplyBehind(index int, dst *gocv.Mat) {
+	p.apply(index, backend.LayerBehind, dst)
+}
+
+// ApplyInFront composites the visible layers placed in front of the subject onto the rendered frame dst for animation
+// frame index
+func (p *LayerPanel) ApplyInFront(index int, dst *gocv.Mat) {
+	p.apply(index, backend.LayerInFront, dst)
+}
+
+func (p *LayerPanel) apply(index int, placement backend.LayerPlacement, dst *gocv.Mat) {
+	layers := backend.Backend.CurrentSettings().Layers
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	// the layers are reopened right after the settings change, until then the stack skips the ones that don't match
+	p.layers.Apply(index, layers, placement, dst)
+}
+
+// updateLayers changes a copy of the project's layers, swaps it in under the settings lock and saves them, then
+// reopens the stack for them
+func (p *LayerPanel) updateLayers(update func(layers []backend.LayerSettings) []backend.LayerSettings) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		settings.Layers = update(append([]backend.LayerSettings{}, settings.Layers...))
+	})
+	SaveProjectSettings()
+	p.reopen()
+	// layers behind the subject are part of the background
+	p.component.BackgroundPanel.Invalidate()
+	p.component.BackgroundPanel.RefreshDisplay()
+}
+
+func NewLayerPanel(component *TopComponent) *LayerPanel {
+	panel := LayerPanel{
+		SequenceToggle:  widget.NewCheck("Image Sequence", nil),
+		LayersContainer: fyne.NewContainerWithLayout(layout.NewVBoxLayout()),
+		component:       component,
+	}
+	addButton := widget.NewButton("Add Layer", func() {
+		panel.OpenFileDialog()
+	})
+
+	addGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), widget.NewLabel("Layers"), addButton, panel.SequenceToggle)
+	layersScroll := widget.NewVScrollContainer(panel.LayersContainer)
+	layersScroll.SetMinSize(fyne.NewSize(0, 250))
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), addGroup, layersScroll)
+
+	panel.Refresh()
+
+	return &panel
+}
@@ -0,0 +1,105 @@
+package components
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+
+	"../backend"
+)
+
+// LumaKeyPanel keys out a black or white backdrop by brightness
+type LumaKeyPanel struct {
+	Container *fyne.Container
+
+	KeyToggle       *widget.Check
+	MatteViewToggle *widget.Check
+	InvertToggle    *widget.Check
+	LowControl      *SliderControl
+	HighControl     *SliderControl
+	SoftnessControl *SliderControl
+
+	// shows what the brightness range keys out as black on white, to set Low and High against the backdrop
+	MatteView bool
+}
+
+// Refresh shows the project's keyed brightness range, inversion and edge softness
+func (p *LumaKeyPanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().LumaKey
+	p.InvertToggle.Checked = settings.Invert
+	p.InvertToggle.Refresh()
+	p.LowControl.SetValue(settings.Low)
+	p.HighControl.SetValue(settings.High)
+	p.SoftnessControl.SetValue(settings.Softness)
+}
+
+// updateSettings changes the project's luma key settings and saves them
+func (p *LumaKeyPanel) updateSettings(update func(settings *backend.LumaKeySettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.LumaKey)
+	})
+	SaveProjectSettings()
+}
+
+func NewLumaKeyPanel(component *TopComponent) *LumaKeyPanel {
+	panel := LumaKeyPanel{}
+	panel.KeyToggle = widget.NewCheck("", func(flag bool) {
+		if flag {
+			component.uncheckKeyToggles(panel.KeyToggle)
+			component.SetCaptureMode(CaptureModeLumaKey)
+		} else {
+			component.SetCaptureMode(CaptureModeNormal)
+		}
+	})
+	panel.MatteViewToggle = widget.NewCheck("", func(flag bool) {
+		panel.MatteView = flag
+	})
+	panel.InvertToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.LumaKeySettings) {
+			settings.Invert = flag
+		})
+	})
+	panel.LowControl = NewSliderControl("Low", "%.0f", 0, 255, 1, func(value float64) {
+		panel.updateSettings(func(settings *backend.LumaKeySettings) {
+			settings.Low = value
+		})
+	})
+	panel.HighControl = NewSliderControl("High", "%.0f", 0, 255, 1, func(value float64) {
+		panel.updateSettings(func(settings *backend.LumaKeySettings) {
+			settings.High = value
+		})
+	})
+	panel.SoftnessControl = NewSliderControl("Edge Softness", "%.1f px", 0, 20, 0.5, func(value float64) {
+		panel.updateSettings(func(settings *backend.LumaKeySettings) {
+			settings.Softness = value
+		})
+	})
+
+	// black backdrops key the dark range, white backdrops the bright one
+	blackButton := widget.NewButton("Black Backdrop", func() {
+		panel.updateSettings(func(settings *backend.LumaKeySettings) {
+			settings.Low, settings.High = 0, backend.DefaultLumaKeySettings.High
+		})
+		panel.Refresh()
+	})
+	whiteButton := widget.NewButton("White Backdrop", func() {
+		panel.updateSettings(func(settings *backend.LumaKeySettings) {
+			settings.Low, settings.High = 255-backend.DefaultLumaKeySettings.High, 255
+		})
+		panel.Refresh()
+	})
+
+	presetGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), blackButton, whiteButton)
+	controlsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Apply Luma Key"), panel.KeyToggle,
+		widget.NewLabel("Show Matte"), panel.MatteViewToggle,
+		panel.LowControl.Label, panel.LowControl.Slider,
+		panel.HighControl.Label, panel.HighControl.Slider,
+		widget.NewLabel("Invert"), panel.InvertToggle,
+		panel.SoftnessControl.Label, panel.SoftnessControl.Slider)
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), presetGroup, controlsGroup)
+
+	panel.Refresh()
+
+	return &panel
+}
@@ -0,0 +1,130 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/storage"
+	"fyne.io/fyne/widget"
+	"path/filepath"
+
+	"../backend"
+)
+
+// LUTPanel grades the project with a 3D LUT, either as frames are captured or only when exporting
+type LUTPanel struct {
+	Container *fyne.Container
+
+	EnableToggle    *widget.Check
+	PathLabel       *widget.Label
+	StrengthControl *SliderControl
+	ModeSelect      *widget.Select
+}
+
+// Refresh shows the project's LUT, how strongly it grades and whether at capture or on export
+func (p *LUTPanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().LUT
+	p.EnableToggle.Checked = settings.Enabled
+	p.EnableToggle.Refresh()
+	p.StrengthControl.SetValue(settings.Strength * 100)
+	p.ModeSelect.Selected = settings.Mode.String()
+	p.ModeSelect.Refresh()
+	p.refreshPathLabel()
+}
+
+func (p *LUTPanel) refreshPathLabel() {
+	path := backend.Backend.CurrentSettings().LUT.Path
+	if path == "" {
+		p.PathLabel.SetText("No LUT loaded")
+		return
+	}
+	lut, err := backend.CachedLUT(path)
+	if err != nil {
+		p.PathLabel.SetText(fmt.Sprintf("%s (%s)", filepath.Base(path), err.Error()))
+		return
+	}
+	name := lut.Title
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	p.PathLabel.SetText(fmt.Sprintf("%s (%d point)", name, lut.Size))
+}
+
+func (p *LUTPanel) OpenFileDialog() {
+	win := fyne.CurrentApp().Driver().AllWindows()[0]
+	open := dialog.NewFileOpen(func(read fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		if read == nil {
+			return
+		}
+		defer read.Close()
+		fileName := read.URI().String()[len(read.URI().Scheme())+3:] // remove "file://"
+		_, err = backend.ReloadLUT(fileName)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("couldn't load %s: %s", filepath.Base(fileName), err.Error()), win)
+			return
+		}
+		p.updateSettings(func(settings *backend.LUTSettings) {
+			settings.Path = fileName
+			settings.Enabled = true
+		})
+		p.Refresh()
+	}, win)
+	open.SetFilter(storage.NewExtensionFileFilter(backend.LUTExtensions))
+	open.Show()
+}
+
+// updateSettings changes the project's LUT settings and saves them
+func (p *LUTPanel) updateSettings(update func(settings *backend.LUTSettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.LUT)
+	})
+	SaveProjectSettings()
+}
+
+func NewLUTPanel() *LUTPanel {
+	panel := LUTPanel{
+		PathLabel: widget.NewLabel(""),
+	}
+	panel.EnableToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.LUTSettings) {
+			settings.Enabled = flag
+		})
+	})
+	loadButton := widget.NewButton("Load .cube LUT", func() {
+		panel.OpenFileDialog()
+	})
+	panel.StrengthControl = NewSliderControl("Strength", "%.0f%%", 5, 100, 5, func(value float64) {
+		panel.updateSettings(func(settings *backend.LUTSettings) {
+			settings.Strength = value / 100
+		})
+	})
+	modeNames := make([]string, 0)
+	for _, mode := range backend.LUTModes {
+		modeNames = append(modeNames, mode.String())
+	}
+	panel.ModeSelect = widget.NewSelect(modeNames, func(choice string) {
+		for _, mode := range backend.LUTModes {
+			if mode.String() == choice {
+				panel.updateSettings(func(settings *backend.LUTSettings) {
+					settings.Mode = mode
+				})
+			}
+		}
+	})
+
+	loadGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), loadButton, panel.PathLabel)
+	controlsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Apply LUT"), panel.EnableToggle,
+		panel.StrengthControl.Label, panel.StrengthControl.Slider,
+		widget.NewLabel("Apply To"), panel.ModeSelect)
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), loadGroup, controlsGroup)
+
+	panel.Refresh()
+
+	return &panel
+}
@@ -0,0 +1,204 @@
+package components
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"math"
+
+	"../backend"
+)
+
+var (
+	// BGR colors blended into onion skin frames when tinting is on
+	previousFrameTint = gocv.NewScalar(0, 0, 255, 0)
+	nextFrameTint     = gocv.NewScalar(0, 255, 0, 0)
+)
+
+const onionSkinTintStrength = 0.4
+
+type OnionSkinPanel struct {
+	Container *fyne.Container
+
+	EnableToggle    *widget.Check
+	TintToggle      *widget.Check
+	PreviousControl *SliderControl
+	NextControl     *SliderControl
+	OpacityControl  *SliderControl
+	FalloffControl  *SliderControl
+
+	// saved frames scaled to the live view, only touched by the capture loop
+	cache        map[string]gocv.Mat
+	cacheSize    image.Point
+	cacheVersion int
+}
+
+// onionSkinLayer is one neighbouring frame and how strongly it shows
+type onionSkinLayer struct {
+	fileName string
+	opacity  float64
+	tint     *gocv.Scalar
+}
+
+// Refresh shows how many frames the project's onion skin ghosts on either side, and how strongly
+func (p *OnionSkinPanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().OnionSkin
+	p.EnableToggle.Checked = settings.Enabled
+	p.EnableToggle.Refresh()
+	p.TintToggle.Checked = settings.Tint
+	p.TintToggle.Refresh()
+	p.PreviousControl.SetValue(float64(settings.Previous))
+	p.NextControl.SetValue(float64(settings.Next))
+	p.OpacityControl.SetValue(settings.Opacity * 100)
+	p.FalloffControl.SetValue(settings.Falloff * 100)
+}
+
+// layers returns the frames around the insertion point, furthest first so the nearest ones are drawn on top.
+// Snapshots are inserted after FilmStrip.Cursor, so the previous frames end at the cursor, or at the last
+// frame when the cursor is unset.
+func (p *OnionSkinPanel) layers(settings backend.OnionSkinSettings) []onionSkinLayer {
+	// this runs on the capture loop, so the frames are read from one copy rather than the list the UI changes
+	frames := backend.Backend.FrameList()
+	insertAt := AnimationFilmStripComponent.InsertionIndex()
+
+	layers := make([]onionSkinLayer, 0)
+	addLayers := func(count int, frameAt func(distance int) int, tint gocv.Scalar) {
+		for distance := count; distance >= 1; distance-- {
+			idx := frameAt(distance)
+			if idx < 0 || idx >= len(frames) {
+				continue
+			}
+			layer := onionSkinLayer{
+				fileName: frames[idx].Filename,
+				opacity:  settings.Opacity * math.Pow(settings.Falloff, float64(distance-1)),
+			}
+			if settings.Tint {
+				pinnedTint := tint
+				layer.tint = &pinnedTint
+			}
+			layers = append(layers, layer)
+		}
+	}
+	addLayers(settings.Next, func(distance int) int { return insertAt + distance - 1 }, nextFrameTint)
+	addLayers(settings.Previous, func(distance int) int { return insertAt - distance }, previousFrameTint)
+	return layers
+}
+
+// Apply ghosts the neighbouring frames over the live view frame dst
+func (p *OnionSkinPanel) Apply(dst *gocv.Mat) {
+	settings := backend.Backend.CurrentSettings().OnionSkin
+	if !settings.Enabled {
+		return
+	}
+	size := image.Pt(dst.Cols(), dst.Rows())
+	version := currentFrameImagesVersion()
+	if size != p.cacheSize || version != p.cacheVersion {
+		p.clearCache()
+		p.cacheSize = size
+		p.cacheVersion = version
+	}
+
+	layers := p.layers(settings)
+	used := map[string]bool{}
+	for _, layer := range layers {
+		used[layer.fileName] = true
+		frame, ok := p.loadFrame(layer.fileName, size)
+		if !ok {
+			continue
+		}
+		if layer.tint != nil {
+			tinted := gocv.NewMatWithSizeFromScalar(*layer.tint, size.Y, size.X, gocv.MatTypeCV8UC3)
+			gocv.AddWeighted(frame, 1-onionSkinTintStrength, tinted, onionSkinTintStrength, 0, &tinted)
+			gocv.AddWeighted(*dst, 1-layer.opacity, tinted, layer.opacity, 0, dst)
+			tinted.Close()
+			continue
+		}
+		gocv.AddWeighted(*dst, 1-layer.opacity, frame, layer.opacity, 0, dst)
+	}
+
+	// frames the cursor moved away from
+	for fileName, frame := range p.cache {
+		if !used[fileName] {
+			frame.Close()
+			delete(p.cache, fileName)
+		}
+	}
+}
+
+func (p *OnionSkinPanel) loadFrame(fileName string, size image.Point) (gocv.Mat, bool) {
+	if frame, ok := p.cache[fileName]; ok {
+		return frame, true
+	}
+	frame, ok := loadPreviewFrame(fileName, size)
+	if ok {
+		p.cache[fileName] = frame
+	}
+	return frame, ok
+}
+
+func (p *OnionSkinPanel) clearCache() {
+	for fileName, frame := range p.cache {
+		frame.Close()
+		delete(p.cache, fileName)
+	}
+}
+
+// updateSettings changes the project's onion skin settings and saves them
+func (p *OnionSkinPanel) updateSettings(update func(settings *backend.OnionSkinSettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.OnionSkin)
+	})
+	SaveProjectSettings()
+}
+
+func NewOnionSkinPanel() *OnionSkinPanel {
+	panel := OnionSkinPanel{
+		cache: map[string]gocv.Mat{},
+	}
+	panel.EnableToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.OnionSkinSettings) {
+			settings.Enabled = flag
+		})
+	})
+	panel.TintToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.OnionSkinSettings) {
+			settings.Tint = flag
+		})
+	})
+	panel.PreviousControl = NewSliderControl("Previous Frames", "%.0f", 0, backend.MaxOnionSkinFrames, 1, func(value float64) {
+		panel.updateSettings(func(settings *backend.OnionSkinSettings) {
+			settings.Previous = int(value)
+		})
+	})
+	panel.NextControl = NewSliderControl("Next Frames", "%.0f", 0, backend.MaxOnionSkinFrames, 1, func(value float64) {
+		panel.updateSettings(func(settings *backend.OnionSkinSettings) {
+			settings.Next = int(value)
+		})
+	})
+	panel.OpacityControl = NewSliderControl("Opacity", "%.0f%%", 5, 100, 5, func(value float64) {
+		panel.updateSettings(func(settings *backend.OnionSkinSettings) {
+			settings.Opacity = value / 100
+		})
+	})
+	// each frame further away shows at this share of the opacity of the one before
+	panel.FalloffControl = NewSliderControl("Falloff", "%.0f%%", 10, 100, 5, func(value float64) {
+		panel.updateSettings(func(settings *backend.OnionSkinSettings) {
+			settings.Falloff = value / 100
+		})
+	})
+
+	controlsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Show Onion Skin"), panel.EnableToggle,
+		widget.NewLabel("Tint Previous/Next"), panel.TintToggle,
+		panel.PreviousControl.Label, panel.PreviousControl.Slider,
+		panel.NextControl.Label, panel.NextControl.Slider,
+		panel.OpacityControl.Label, panel.OpacityControl.Slider,
+		panel.FalloffControl.Label, panel.FalloffControl.Slider)
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), controlsGroup)
+
+	panel.Refresh()
+
+	return &panel
+}
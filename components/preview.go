@@ -0,0 +1,169 @@
+package components
+
+import (
+	"fyne.io/fyne/canvas"
+	"gocv.io/x/gocv"
+	"image"
+	"log"
+	"sync"
+	"time"
+
+	"../backend"
+	"../config"
+)
+
+const (
+	// the live view is redrawn at most this often
+	previewFps = 30
+
+	// how often the live view reports the frame rate it achieved
+	previewStatsInterval = 10 * time.Second
+)
+
+// previewStats accounts for the time spent on each live view frame, so slow machines show up in the log
+type previewStats struct {
+	since        time.Time
+	frames       int
+	overBudget   int
+	renderTime   time.Duration
+	longestFrame time.Duration
+}
+
+func (s *previewStats) record(renderTime time.Duration, budget time.Duration) {
+	if s.since.IsZero() {
+		s.since = time.Now()
+	}
+	s.frames++
+	s.renderTime += renderTime
+	if renderTime > budget {
+		s.overBudget++
+	}
+	if renderTime > s.longestFrame {
+		s.longestFrame = renderTime
+	}
+	elapsed := time.Since(s.since)
+	if elapsed < previewStatsInterval {
+		return
+	}
+	log.Printf("live view: %.1f fps, %.1f ms average render, %d ms longest, %d of %d frames over budget",
+		float64(s.frames)/elapsed.Seconds(), float64(s.renderTime.Microseconds())/float64(s.frames)/1000,
+		s.longestFrame.Milliseconds(), s.overBudget, s.frames)
+	*s = previewStats{since: time.Now()}
+}
+
+// previewSize is the size of the captured frame letterboxed into the live view
+func previewSize() image.Point {
+	width, height := backend.CurrentResolution().FitInto(config.WebcamDisplayWidth, config.WebcamDisplayHeight)
+	return image.Pt(width, height)
+}
+
+// displayToPreview maps a point on the letterboxed live view to the corresponding pixel of the preview image
+func displayToPreview(x int, y int) image.Point {
+	size := previewSize()
+	return image.Pt(x-(config.WebcamDisplayWidth-size.X)/2, y-(config.WebcamDisplayHeight-size.Y)/2)
+}
+
+var (
+	// counts the times saved frame images were written over, so the live view's caches of them know to reload.
+	// Guarded by frameImagesLock.
+	frameImagesVersion int
+	frameImagesLock    sync.Mutex
+)
+
+// frameImagesChanged tells the live view that saved frame images were written over since it loaded them
+func frameImagesChanged() {
+	frameImagesLock.Lock()
+	defer frameImagesLock.Unlock()
+	frameImagesVersion++
+}
+
+// currentFrameImagesVersion changes whenever saved frame images were written over
+func currentFrameImagesVersion() int {
+	frameImagesLock.Lock()
+	defer frameImagesLock.Unlock()
+	return frameImagesVersion
+}
+
+// loadPreviewFrame reads a saved frame scaled to the live view. The caller must close it.
+func loadPreviewFrame(fileName string, size image.Point) (gocv.Mat, bool) {
+	frame := gocv.IMRead(fileName, gocv.IMReadColor)
+	if frame.Empty() {
+		log.Printf("couldn't read frame from %s", fileName)
+		frame.Close()
+		return gocv.Mat{}, false
+	}
+	defer frame.Close()
+	backend.ConformToResolution(&frame, backend.CurrentResolution()) // frames shot before a resolution change
+	resized := gocv.NewMat()
+	gocv.Resize(frame, &resized, size, 0, 0, gocv.InterpolationArea)
+	return resized, true
+}
+
+// renderPreview renders a raw camera frame the way a snapshot would look, at the size of the live view.
+// The zoomed region is cropped and scaled down before keying, so the keyer only works on display sized images.
+func (c *TopComponent) renderPreview(rawMat *gocv.Mat, dst *gocv.Mat) {
+	rect := c.zoomRect(rawMat.Cols(), rawMat.Rows())
+	size := previewSize()
+	if c.FlipControl.Apply(dst, size) {
+		c.GuidesPanel.Apply(dst)
+		return
+	}
+	region := rawMat.Region(rect)
+	gocv.Resize(region, dst, size, 0, 0, gocv.InterpolationLinear)
+	region.Close()
+
+	garbage := garbageMask(rect, image.Pt(rawMat.Cols(), rawMat.Rows()))
+	switch c.CaptureMode {
+	case CaptureModeChromaKey:
+		keyed := gocv.NewMat()
+		c.applyChromaKey(*dst, c.BackgroundPanel.PreviewBackground(AnimationFilmStripComponent.InsertionIndex(), rect, size), garbage, c.ChromaPanel.MatteView, &keyed)
+		keyed.CopyTo(dst)
+		keyed.Close()
+	case CaptureModeDifferenceKey:
+		keyed := gocv.NewMat()
+		if c.DifferenceKeyPanel.Apply(*dst, rect, c.BackgroundPanel.PreviewBackground(AnimationFilmStripComponent.InsertionIndex(), rect, size), garbage, c.DifferenceKeyPanel.MatteView, &keyed) {
+			keyed.CopyTo(dst)
+		}
+		keyed.Close()
+	case CaptureModeLumaKey:
+		keyed := gocv.NewMat()
+		backend.ApplyLumaKey(*dst, c.BackgroundPanel.PreviewBackground(AnimationFilmStripComponent.InsertionIndex(), rect, size), backend.Backend.CurrentSettings().LumaKey, garbage, c.LumaKeyPanel.MatteView, &keyed)
+		keyed.CopyTo(dst)
+		keyed.Close()
+	}
+	if !c.matteViewShown() {
+		c.LayerPanel.ApplyInFront(AnimationFilmStripComponent.InsertionIndex(), dst)
+	}
+	backend.ApplyFilters(backend.Backend.CurrentSettings().Filters, dst)
+	backend.ApplyLUT(backend.Backend.CurrentSettings().LUT, backend.LUTModeCapture, dst)
+
+	// live view only overlays, never part of a snapshot
+	c.OnionSkinPanel.Apply(dst)
+	c.ReferencePanel.Apply(dst)
+	c.GarbageMattePanel.Apply(dst, garbage)
+	c.ZoomPanel.Apply(dst)
+	c.GuidesPanel.Apply(dst)
+}
+
+// matteViewShown reports whether the live view shows the matte of the key in use rather than the keyed frame
+func (c *TopComponent) matteViewShown() bool {
+	switch c.CaptureMode {
+	case CaptureModeChromaKey:
+		return c.ChromaPanel.MatteView
+	case CaptureModeDifferenceKey:
+		return c.DifferenceKeyPanel.MatteView
+	case CaptureModeLumaKey:
+		return c.LumaKeyPanel.MatteView
+	}
+	return false
+}
+
+// showPreview hands a BGR frame to the live view as a decoded image, so fyne doesn't have to decode a PNG per frame.
+// A new image is allocated every frame because the renderer may still be drawing the previous one.
+func (c *TopComponent) showPreview(previewMat gocv.Mat, rgbaMat *gocv.Mat) {
+	gocv.CvtColor(previewMat, rgbaMat, gocv.ColorBGRToRGBA)
+	img := image.NewRGBA(image.Rect(0, 0, rgbaMat.Cols(), rgbaMat.Rows()))
+	copy(img.Pix, rgbaMat.DataPtrUint8())
+	c.WebcamImage.Image = img
+	canvas.Refresh(c.WebcamImage)
+}
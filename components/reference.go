@@ -0,0 +1,294 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/storage"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"../backend"
+)
+
+type ReferencePanel struct {
+	Container *fyne.Container
+
+	EnableToggle     *widget.Check
+	PathLabel        *widget.Label
+	OpacityControl   *SliderControl
+	ScaleControl     *SliderControl
+	OffsetXControl   *SliderControl
+	OffsetYControl   *SliderControl
+	BlendSelect      *widget.Select
+	FrameOffsetEntry *widget.Entry
+
+	// the loaded reference, replaced from the UI while the capture loop draws it
+	lock       sync.Mutex
+	source     backend.FrameSource
+	frame      gocv.Mat
+	frameIndex int
+	// frame scaled for the live view, rebuilt when the frame or its size changes
+	scaled     gocv.Mat
+	scaledSize image.Point
+}
+
+// Refresh shows the project's reference file and how it is blended, placed and synced over the live view
+func (p *ReferencePanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().Reference
+	p.EnableToggle.Checked = settings.Enabled
+	p.EnableToggle.Refresh()
+	p.refreshPathLabel()
+	p.OpacityControl.SetValue(settings.Opacity * 100)
+	p.ScaleControl.SetValue(settings.Scale * 100)
+	p.OffsetXControl.SetValue(settings.OffsetX * 100)
+	p.OffsetYControl.SetValue(settings.OffsetY * 100)
+	p.BlendSelect.Selected = settings.Blend.String()
+	p.BlendSelect.Refresh()
+	if p.FrameOffsetEntry.Text != strconv.Itoa(settings.FrameOffset) {
+		p.FrameOffsetEntry.SetText(strconv.Itoa(settings.FrameOffset))
+	}
+}
+
+func (p *ReferencePanel) refreshPathLabel() {
+	path := backend.Backend.CurrentSettings().Reference.Path
+	if path == "" {
+		p.PathLabel.SetText("No reference loaded")
+		return
+	}
+	p.lock.Lock()
+	source := p.source
+	p.lock.Unlock()
+	if source == nil {
+		p.PathLabel.SetText(fmt.Sprintf("%s (missing)", filepath.Base(path)))
+		return
+	}
+	p.PathLabel.SetText(fmt.Sprintf("%s (%d frames)", filepath.Base(path), source.FrameCount()))
+}
+
+// Open loads the reference at path, replacing the current one. An empty path unloads it.
+func (p *ReferencePanel) Open(path string) error {
+	var source backend.FrameSource
+	if path != "" {
+		var err error
+		source, err = backend.OpenFrameSource(path)
+		if err != nil {
+			return err
+		}
+	}
+	p.lock.Lock()
+	if p.source != nil {
+		p.source.Close()
+	}
+	p.source = source
+	p.frameIndex = -1
+	p.scaledSize = image.Point{}
+	p.lock.Unlock()
+	return nil
+}
+
+// ApplyProjectSettings reopens the project's reference, if any
+func (p *ReferencePanel) ApplyProjectSettings() {
+	err := p.Open(backend.Backend.CurrentSettings().Reference.Path)
+	if err != nil {
+		log.Printf("error opening reference: %s", err.Error())
+	}
+	p.Refresh()
+}
+
+func (p *ReferencePanel) OpenFileDialog() {
+	win := fyne.CurrentApp().Driver().AllWindows()[0]
+	open := dialog.NewFileOpen(func(read fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		if read == nil {
+			return
+		}
+		defer read.Close()
+		fileName := read.URI().String()[len(read.URI().Scheme())+3:] // remove "file://"
+		err = p.Open(fileName)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		p.updateSettings(func(settings *backend.ReferenceSettings) {
+			settings.Path = fileName
+			settings.Enabled = true
+		})
+		p.Refresh()
+	}, win)
+
+	extensions := append(append([]string{}, backend.ImageExtensions...), backend.VideoExtensions...)
+	open.SetFilter(storage.NewExtensionFileFilter(extensions))
+	open.Show()
+}
+
+// Apply overlays the reference on the live view frame dst. Videos show the frame matching the position a
+// snapshot would be inserted at.
+func (p *ReferencePanel) Apply(dst *gocv.Mat) {
+	settings := backend.Backend.CurrentSettings().Reference
+	if !settings.Enabled {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.source == nil {
+		return
+	}
+
+	frameIndex := AnimationFilmStripComponent.InsertionIndex() + settings.FrameOffset
+	if frameIndex != p.frameIndex {
+		if !p.source.Frame(frameIndex, &p.frame) {
+			return
+		}
+		p.frameIndex = frameIndex
+		p.scaledSize = image.Point{}
+	}
+
+	// fit the reference into the live view, then apply its own scale and offset
+	size := image.Pt(dst.Cols(), dst.Rows())
+	fitWidth, fitHeight := backend.Resolution{Width: p.frame.Cols(), Height: p.frame.Rows()}.FitInto(size.X, size.Y)
+	scaledSize := image.Pt(int(float64(fitWidth)*settings.Scale), int(float64(fitHeight)*settings.Scale))
+	if scaledSize.X < 1 || scaledSize.Y < 1 {
+		return
+	}
+	if scaledSize != p.scaledSize {
+		gocv.Resize(p.frame, &p.scaled, scaledSize, 0, 0, gocv.InterpolationLinear)
+		p.scaledSize = scaledSize
+	}
+	center := image.Pt(size.X/2+int(settings.OffsetX*float64(size.X)), size.Y/2+int(settings.OffsetY*float64(size.Y)))
+	placed := image.Rectangle{Min: center.Sub(scaledSize.Div(2))}
+	placed.Max = placed.Min.Add(scaledSize)
+	visible := placed.Intersect(image.Rect(0, 0, size.X, size.Y))
+	if visible.Empty() {
+		return
+	}
+
+	dstRegion := dst.Region(visible)
+	defer dstRegion.Close()
+	referenceRegion := p.scaled.Region(visible.Sub(placed.Min))
+	defer referenceRegion.Close()
+	blended := gocv.NewMat()
+	defer blended.Close()
+	blendImages(dstRegion, referenceRegion, settings.Blend, &blended)
+	gocv.AddWeighted(dstRegion, 1-settings.Opacity, blended, settings.Opacity, 0, &dstRegion)
+}
+
+// blendImages combines overlay with base according to mode, before any opacity is applied
+func blendImages(base gocv.Mat, overlay gocv.Mat, mode backend.BlendMode, dst *gocv.Mat) {
+	switch mode {
+	case backend.BlendDifference:
+		gocv.AbsDiff(base, overlay, dst)
+	case backend.BlendAdd:
+		gocv.Add(base, overlay, dst)
+	case backend.BlendLighten:
+		gocv.Max(base, overlay, dst)
+	case backend.BlendDarken:
+		gocv.Min(base, overlay, dst)
+	default:
+		overlay.CopyTo(dst)
+	}
+}
+
+// updateSettings changes the project's reference settings and saves them
+func (p *ReferencePanel) updateSettings(update func(settings *backend.ReferenceSettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.Reference)
+	})
+	SaveProjectSettings()
+}
+
+func NewReferencePanel() *ReferencePanel {
+	panel := ReferencePanel{
+		PathLabel:        widget.NewLabel(""),
+		FrameOffsetEntry: widget.NewEntry(),
+		frame:            gocv.NewMat(),
+		frameIndex:       -1,
+		scaled:           gocv.NewMat(),
+	}
+	panel.EnableToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateSettings(func(settings *backend.ReferenceSettings) {
+			settings.Enabled = flag
+		})
+	})
+	loadButton := widget.NewButton("Load Reference", func() {
+		panel.OpenFileDialog()
+	})
+	clearButton := widget.NewButton("Clear", func() {
+		err := panel.Open("")
+		if err != nil {
+			log.Printf("error closing reference: %s", err.Error())
+		}
+		panel.updateSettings(func(settings *backend.ReferenceSettings) {
+			settings.Path = ""
+		})
+		panel.refreshPathLabel()
+	})
+	panel.OpacityControl = NewSliderControl("Opacity", "%.0f%%", 5, 100, 5, func(value float64) {
+		panel.updateSettings(func(settings *backend.ReferenceSettings) {
+			settings.Opacity = value / 100
+		})
+	})
+	// scale is relative to the reference fitted into the live view, offsets to the live view's size
+	panel.ScaleControl = NewSliderControl("Scale", "%.0f%%", 10, 400, 5, func(value float64) {
+		panel.updateSettings(func(settings *backend.ReferenceSettings) {
+			settings.Scale = value / 100
+		})
+	})
+	panel.OffsetXControl = NewSliderControl("X", "%.0f%%", -100, 100, 1, func(value float64) {
+		panel.updateSettings(func(settings *backend.ReferenceSettings) {
+			settings.OffsetX = value / 100
+		})
+	})
+	panel.OffsetYControl = NewSliderControl("Y", "%.0f%%", -100, 100, 1, func(value float64) {
+		panel.updateSettings(func(settings *backend.ReferenceSettings) {
+			settings.OffsetY = value / 100
+		})
+	})
+	blendNames := make([]string, 0)
+	for _, mode := range backend.BlendModes {
+		blendNames = append(blendNames, mode.String())
+	}
+	panel.BlendSelect = widget.NewSelect(blendNames, func(choice string) {
+		for _, mode := range backend.BlendModes {
+			if mode.String() == choice {
+				panel.updateSettings(func(settings *backend.ReferenceSettings) {
+					settings.Blend = mode
+				})
+			}
+		}
+	})
+	panel.FrameOffsetEntry.OnChanged = func(text string) {
+		frameOffset, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			return
+		}
+		panel.updateSettings(func(settings *backend.ReferenceSettings) {
+			settings.FrameOffset = frameOffset
+		})
+	}
+
+	buttonGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), loadButton, clearButton, panel.PathLabel)
+	controlsGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Show Reference"), panel.EnableToggle,
+		panel.OpacityControl.Label, panel.OpacityControl.Slider,
+		widget.NewLabel("Blend Mode"), panel.BlendSelect,
+		panel.ScaleControl.Label, panel.ScaleControl.Slider,
+		panel.OffsetXControl.Label, panel.OffsetXControl.Slider,
+		panel.OffsetYControl.Label, panel.OffsetYControl.Slider,
+		widget.NewLabel("Video Frame Offset"), panel.FrameOffsetEntry)
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), buttonGroup, controlsGroup)
+
+	panel.Refresh()
+
+	return &panel
+}
@@ -0,0 +1,172 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"../backend"
+	"../util"
+)
+
+// keeps the key or framing each frame was shot with when rendering frames again
+const renderAsShot = "As Shot"
+
+const (
+	renderCurrentFraming = "Current"
+	renderCameraMove     = "Camera Move"
+)
+
+// liveSources render snapshots from the background, layers and clean plate the live view keys with, so a snapshot
+// looks like the live view did. They are loaded for the project's current settings, which snapshots are rendered with.
+type liveSources struct {
+	component *TopComponent
+}
+
+func (s liveSources) RenderBackground(index int, settings backend.RenderSettings, dst *gocv.Mat) {
+	s.component.BackgroundPanel.Background(index, dst)
+}
+
+func (s liveSources) ApplyLayers(index int, settings backend.RenderSettings, placement backend.LayerPlacement, dst *gocv.Mat) {
+	s.component.LayerPanel.apply(index, placement, dst)
+}
+
+func (s liveSources) CleanPlate(dst *gocv.Mat) bool {
+	return s.component.DifferenceKeyPanel.CleanPlate(dst)
+}
+
+// OpenReRenderDialog asks for a range of frames and renders them again from their raw camera frames with the
+// project's current settings
+func (c *TopComponent) OpenReRenderDialog() {
+	if backend.Backend.Name == "" {
+		DisplayUserTip("Please create/open a project first.")
+		return
+	}
+	frameCount := len(backend.Backend.Frames)
+	if frameCount == 0 {
+		DisplayUserTip("The project has no frames to render yet.")
+		return
+	}
+	firstEntry := widget.NewEntry()
+	firstEntry.SetText("1")
+	lastEntry := widget.NewEntry()
+	lastEntry.SetText(strconv.Itoa(frameCount))
+	keyNames := []string{renderAsShot}
+	for _, mode := range backend.KeyModes {
+		keyNames = append(keyNames, mode.String())
+	}
+	keySelect := widget.NewSelect(keyNames, nil)
+	keySelect.Selected = renderAsShot
+	framingSelect := widget.NewSelect([]string{renderAsShot, renderCurrentFraming, renderCameraMove}, nil)
+	framingSelect.Selected = renderAsShot
+	content := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		widget.NewLabel("Frames are rendered again from the camera with the current key,\nbackground, layer, filter and colour grade settings."),
+		fyne.NewContainerWithLayout(layout.NewFormLayout(),
+			widget.NewLabel("From Frame"), firstEntry,
+			widget.NewLabel("To Frame"), lastEntry,
+			widget.NewLabel("Key"), keySelect,
+			widget.NewLabel("Framing"), framingSelect))
+	win := fyne.CurrentApp().Driver().AllWindows()[0]
+	dialog.ShowCustomConfirm("Re-render Frames", "Re-render", "Cancel", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		first, errFirst := strconv.Atoi(strings.TrimSpace(firstEntry.Text))
+		last, errLast := strconv.Atoi(strings.TrimSpace(lastEntry.Text))
+		if errFirst != nil || errLast != nil || first < 1 || last > len(backend.Backend.Frames) || first > last {
+			DisplayUserTip(fmt.Sprintf("Choose frames between 1 and %d.", len(backend.Backend.Frames)))
+			return
+		}
+		// rendered in the background with the settings as they are now
+		settings := backend.Backend.CurrentSettings()
+		render := func(index int, shot backend.RenderSettings) backend.RenderSettings {
+			key, frameFraming := shot.Key, shot.Framing
+			for _, mode := range backend.KeyModes {
+				if mode.String() == keySelect.Selected {
+					key = mode
+				}
+			}
+			switch framingSelect.Selected {
+			case renderCurrentFraming:
+				frameFraming = settings.Framing
+			case renderCameraMove:
+				if moved, ok := settings.CameraMove.FramingAt(index); ok {
+					frameFraming = moved
+				}
+			}
+			return settings.RenderSettings(key, frameFraming)
+		}
+		go c.reRenderFrames(first-1, last-1, render)
+	}, win)
+}
+
+// reRenderFrames renders frames first to last again from their raw camera frames, with the settings render returns
+// for the index and settings each was shot with. Frames shot before raw frames were kept are left as they are.
+func (c *TopComponent) reRenderFrames(first int, last int, render func(index int, shot backend.RenderSettings) backend.RenderSettings) {
+	defer util.LogPerf(fmt.Sprintf("TopComponent.reRenderFrames(%d, %d)", first, last), time.Now())
+	progressBar := dialog.NewProgress("Rendering Frames", "Please wait while frames are rendered.", fyne.CurrentApp().Driver().AllWindows()[0])
+	progressBar.Show()
+	renderer := backend.NewRenderer()
+	defer renderer.Close()
+	// snapshots taken meanwhile don't move the frames being rendered
+	frames := backend.Backend.FrameList()
+	if last >= len(frames) { // frames deleted since the range was chosen
+		last = len(frames) - 1
+	}
+	if first > last {
+		first = last + 1
+	}
+	frames = frames[first : last+1]
+	rendered, skipped := 0, 0
+	for idx, frame := range frames {
+		progressBar.SetValue(float64(idx) / float64(len(frames)))
+		shot, ok := backend.Backend.FrameRender(frame)
+		if !ok {
+			skipped++
+			continue
+		}
+		settings := render(first+idx, shot)
+		err := c.reRenderFrame(renderer, first+idx, frame, settings)
+		if err != nil {
+			log.Printf("error rendering frame %d: %s", first+idx+1, err.Error())
+			skipped++
+			continue
+		}
+		backend.Backend.SetFrameRender(frame, settings)
+		rendered++
+	}
+	progressBar.SetValue(1.0)
+	progressBar.Hide()
+	// the frames were written over under the names the live view cached them by
+	frameImagesChanged()
+
+	err := backend.Backend.Save()
+	if err != nil {
+		log.Printf("error saving project: %s", err.Error())
+	}
+	AnimationFilmStripComponent.SyncToBackend()
+	if skipped > 0 {
+		DisplayUserTip(fmt.Sprintf("Rendered %d frames, %d were left as they were.\nFrames shot before raw camera frames were kept can't be rendered again.", rendered, skipped))
+		return
+	}
+	DisplayUserTip(fmt.Sprintf("Rendered %d frames.", rendered))
+}
+
+// reRenderFrame renders the frame at index from its raw camera frame and saves it over the old image and thumbnail
+func (c *TopComponent) reRenderFrame(renderer *backend.Renderer, index int, frame *backend.Frame, settings backend.RenderSettings) error {
+	raw := gocv.IMRead(frame.RawFilename, gocv.IMReadColor)
+	defer raw.Close()
+	if raw.Empty() {
+		return fmt.Errorf("couldn't read raw frame from %s", frame.RawFilename)
+	}
+	rendered := renderer.Render(raw, index, settings)
+	defer rendered.Close()
+	return c.saveFrameImage(&rendered, frame.Filename, frame.ThumbnailFilename)
+}
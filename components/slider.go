@@ -0,0 +1,50 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne/widget"
+)
+
+// SliderControl is a slider for a numeric setting with a label showing the setting's name and value. Panels lay out
+// the label and slider as a row of a form.
+type SliderControl struct {
+	Name string
+	// formats the value in the label, e.g. "%.1f px"
+	Format string
+	// shown instead of a zero value if set, for settings that 0 turns off
+	Zero   string
+	Label  *widget.Label
+	Slider *widget.Slider
+}
+
+// SetValue moves the slider to value, without calling back, and shows it in the label
+func (c *SliderControl) SetValue(value float64) {
+	c.Slider.Value = value
+	c.Slider.Refresh()
+	c.showValue(value)
+}
+
+func (c *SliderControl) showValue(value float64) {
+	if value == 0 && c.Zero != "" {
+		c.Label.SetText(fmt.Sprintf("%s (%s)", c.Name, c.Zero))
+		return
+	}
+	c.Label.SetText(fmt.Sprintf("%s (%s)", c.Name, fmt.Sprintf(c.Format, value)))
+}
+
+// NewSliderControl returns a control sliding from min to max in steps of step, calling changed with each value the
+// user picks
+func NewSliderControl(name string, format string, min float64, max float64, step float64, changed func(value float64)) *SliderControl {
+	control := SliderControl{
+		Name:   name,
+		Format: format,
+		Label:  widget.NewLabel(name),
+		Slider: widget.NewSlider(min, max),
+	}
+	control.Slider.Step = step
+	control.Slider.OnChanged = func(value float64) {
+		control.showValue(value)
+		changed(value)
+	}
+	return &control
+}
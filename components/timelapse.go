@@ -0,0 +1,196 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"../backend"
+)
+
+// frames older than this are considered stalled and are not saved by the intervalometer
+const timelapseStallTimeout = 2 * time.Second
+
+type TimelapsePanel struct {
+	Container      *fyne.Container
+	Intervalometer *backend.Intervalometer
+
+	IntervalEntry   *widget.Entry
+	CountEntry      *widget.Entry
+	EndTimeEntry    *widget.Entry
+	StartDelayEntry *widget.Entry
+
+	StartButton *widget.Button
+	PauseButton *widget.Button
+	// countdown shown under the live view while a session runs
+	StatusLabel *widget.Label
+	statusText  string
+}
+
+// Refresh fills the schedule entries with the project's last time-lapse schedule
+func (p *TimelapsePanel) Refresh() {
+	settings := backend.Backend.CurrentSettings().Timelapse
+	p.IntervalEntry.SetText(strconv.Itoa(settings.IntervalSeconds))
+	p.CountEntry.SetText(strconv.Itoa(settings.Count))
+	p.EndTimeEntry.SetText(settings.EndTime)
+	p.StartDelayEntry.SetText(strconv.Itoa(settings.StartDelaySeconds))
+}
+
+// readSettings parses the schedule typed into the panel
+func (p *TimelapsePanel) readSettings() (backend.TimelapseSettings, error) {
+	settings := backend.TimelapseSettings{EndTime: strings.TrimSpace(p.EndTimeEntry.Text)}
+	fields := []struct {
+		name  string
+		entry *widget.Entry
+		value *int
+	}{
+		{"interval", p.IntervalEntry, &settings.IntervalSeconds},
+		{"frame count", p.CountEntry, &settings.Count},
+		{"start delay", p.StartDelayEntry, &settings.StartDelaySeconds},
+	}
+	for _, field := range fields {
+		text := strings.TrimSpace(field.entry.Text)
+		if text == "" {
+			continue
+		}
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return settings, fmt.Errorf("%s must be a whole number", field.name)
+		}
+		*field.value = value
+	}
+	return settings, nil
+}
+
+func (p *TimelapsePanel) Start() {
+	if backend.Backend.Name == "" {
+		DisplayUserTip("Please create/open a project before starting a time-lapse.")
+		return
+	}
+	if backend.CurrentCamera() == nil {
+		DisplayUserTip("Please connect a camera first.")
+		return
+	}
+	settings, err := p.readSettings()
+	if err == nil {
+		err = p.Intervalometer.Start(settings, time.Now())
+	}
+	if err != nil {
+		DisplayUserTip(err.Error())
+		return
+	}
+	backend.Backend.UpdateSettings(func(projectSettings *backend.ProjectSettings) {
+		projectSettings.Timelapse = settings
+	})
+	SaveProjectSettings()
+	log.Printf("time-lapse started: %+v", settings)
+	p.StartButton.SetText("Stop")
+	p.PauseButton.SetText("Pause")
+	p.PauseButton.Enable()
+	p.StatusLabel.Show()
+}
+
+func (p *TimelapsePanel) Stop() {
+	p.Intervalometer.Stop()
+	log.Printf("time-lapse stopped")
+	p.StartButton.SetText("Start")
+	p.PauseButton.SetText("Pause")
+	p.PauseButton.Disable()
+	p.statusText = ""
+	p.StatusLabel.SetText("")
+	p.StatusLabel.Hide()
+}
+
+func (p *TimelapsePanel) TogglePause() {
+	if p.Intervalometer.IsPaused() {
+		p.Intervalometer.Resume(time.Now())
+		p.PauseButton.SetText("Pause")
+	} else {
+		p.Intervalometer.Pause(time.Now())
+		p.PauseButton.SetText("Resume")
+	}
+	p.RefreshStatus()
+}
+
+// RefreshStatus updates the countdown, only touching the label when the text changes
+func (p *TimelapsePanel) RefreshStatus() {
+	status := p.Intervalometer.Status(time.Now())
+	if status == p.statusText {
+		return
+	}
+	p.statusText = status
+	p.StatusLabel.SetText(status)
+}
+
+// runIntervalometer takes the time-lapse snapshot that is due, if any. It is called on every capture loop
+// iteration, including the ones that skip the live view because the camera is disabled or reconnecting.
+// Snapshots come straight from the capture goroutine, so it keeps working while the window is minimised.
+func (c *TopComponent) runIntervalometer() {
+	panel := c.TimelapsePanel
+	if !panel.Intervalometer.IsRunning() {
+		return
+	}
+	if panel.Intervalometer.Due(time.Now()) {
+		lastFrameTime := backend.LastFrameTime()
+		if backend.IsCameraLost() || time.Since(lastFrameTime) > timelapseStallTimeout {
+			log.Printf("time-lapse snapshot missed: camera has not delivered a frame since %s", lastFrameTime.Format(time.Stamp))
+			panel.Intervalometer.Miss()
+		} else if err := c.Snapshot(); err != nil {
+			log.Printf("time-lapse snapshot failed: %s", err.Error())
+			panel.Intervalometer.Miss()
+		} else {
+			panel.Intervalometer.Shot()
+			AnimationFilmStripComponent.Tail()
+			AnimationFilmStripComponent.SyncToBackend()
+		}
+		if panel.Intervalometer.Finished() {
+			panel.RefreshStatus()
+			log.Printf("time-lapse finished: %s", panel.statusText)
+			panel.Stop()
+			return
+		}
+	}
+	panel.RefreshStatus()
+}
+
+func NewTimelapsePanel() *TimelapsePanel {
+	panel := TimelapsePanel{
+		Intervalometer:  &backend.Intervalometer{},
+		IntervalEntry:   widget.NewEntry(),
+		CountEntry:      widget.NewEntry(),
+		EndTimeEntry:    widget.NewEntry(),
+		StartDelayEntry: widget.NewEntry(),
+		StatusLabel:     widget.NewLabel(""),
+	}
+	panel.CountEntry.SetPlaceHolder("0 for no limit")
+	panel.EndTimeEntry.SetPlaceHolder("HH:MM, optional")
+	panel.StartButton = widget.NewButton("Start", func() {
+		if panel.Intervalometer.IsRunning() {
+			panel.Stop()
+		} else {
+			panel.Start()
+		}
+	})
+	panel.PauseButton = widget.NewButton("Pause", func() {
+		panel.TogglePause()
+	})
+	panel.PauseButton.Disable()
+	panel.StatusLabel.Hide()
+
+	scheduleGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Interval (seconds)"), panel.IntervalEntry,
+		widget.NewLabel("Frames"), panel.CountEntry,
+		widget.NewLabel("Stop At"), panel.EndTimeEntry,
+		widget.NewLabel("Start Delay (seconds)"), panel.StartDelayEntry)
+	buttonGroup := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), panel.StartButton, panel.PauseButton)
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), scheduleGroup, buttonGroup)
+
+	panel.Refresh()
+
+	return &panel
+}
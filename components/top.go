@@ -1,14 +1,12 @@
 package components
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"fyne.io/fyne"
 	"fyne.io/fyne/canvas"
 	"fyne.io/fyne/dialog"
 	"fyne.io/fyne/layout"
-	"fyne.io/fyne/storage"
 	"fyne.io/fyne/widget"
 	"github.com/amarburg/go-fast-png"
 	"github.com/google/uuid"
@@ -16,8 +14,8 @@ import (
 	"image"
 	"image/color"
 	"log"
-	"math"
 	"os"
+	"sync"
 	"time"
 
 	"../backend"
@@ -39,120 +37,39 @@ type TopComponent struct {
 	WebcamImageContainer *fyne.Container
 	WebcamImage          *canvas.Image
 
-	// contextual panel
-	ContextPane *fyne.Container
-
-	ProjectPanel    *Gallery
-	ChromaPanel     *ChromaPanel
-	ZoomPanel       *ZoomPanel
-	BackgroundPanel *BackgroundPanel
-}
-
-type ChromaPanel struct {
-	ChromaFilterToggle *widget.Check
-	ColorPickerToggle  *widget.Check
-
-	RedSlider   *widget.Slider
-	GreenSlider *widget.Slider
-	BlueSlider  *widget.Slider
-	FuzzSlider  *widget.Slider
-
-	PreviewColor *canvas.Rectangle
-
-	Container *fyne.Container
-}
-
-type ZoomPanel struct {
-	Container  *fyne.Container
-	ZoomSlider *widget.Slider
-	ZoomLabel  *widget.Label
-}
-
-func (c *ChromaPanel) GetChromaKey() color.Color {
-	clr := color.RGBA{
-		R: uint8(c.RedSlider.Value),
-		G: uint8(c.GreenSlider.Value),
-		B: uint8(c.BlueSlider.Value),
-		A: 0,
-	}
-	return clr
-}
-
-type BackgroundPanel struct {
-	Container            *fyne.Container
-	BackgroundImageMat   *gocv.Mat
-	BackgroundImage      *canvas.Image
-	BackgroundResizedHsv *gocv.Mat
-}
-
-func (b *BackgroundPanel) RefreshDisplay() {
-	if b.BackgroundImageMat != nil {
-		img, err := b.BackgroundImageMat.ToImage()
-		if err != nil {
-			log.Printf("error: %s", err.Error())
-			return
-		}
-		b.BackgroundImage.Image = img
-		b.BackgroundImage.SetMinSize(fyne.NewSize(480, 270))
-		canvas.Refresh(b.BackgroundImage)
-	}
-}
-
-func (b *BackgroundPanel) GenerateResizedBackground() {
-	backgroundResized := gocv.NewMat()
-	backgroundResizedHsv := gocv.NewMat()
-	gocv.Resize(*b.BackgroundImageMat, &backgroundResized, image.Pt(config.WebcamCaptureWidth, config.WebcamCaptureHeight), 0, 0, gocv.InterpolationLanczos4)
-	gocv.CvtColor(backgroundResized, &backgroundResizedHsv, gocv.ColorBGRToHSV)
-	b.BackgroundResizedHsv = &backgroundResizedHsv
-}
-
-func (b *BackgroundPanel) LoadFile(read fyne.URIReadCloser) {
-	defer read.Close()
-	fileName := read.URI().String()[len(read.URI().Scheme())+3:] // remove "file://"
-	background := gocv.IMRead(fileName, gocv.IMReadColor)
-	b.BackgroundImageMat = &background
-}
-
-func (b *BackgroundPanel) OpenFileDialog() {
-	win := fyne.CurrentApp().Driver().AllWindows()[0]
-	open := dialog.NewFileOpen(func(read fyne.URIReadCloser, err error) {
-		if err != nil {
-			dialog.ShowError(err, win)
-			return
-		}
-		if read == nil {
-			return
-		}
+	// shown over the live view while the camera is lost
+	DisconnectedOverlay *fyne.Container
+	DisconnectedText    *canvas.Text
+	cameraDisconnected  bool
 
-		b.LoadFile(read)
-		b.GenerateResizedBackground()
-		b.RefreshDisplay()
-	}, win)
+	// sequence number of the last camera frame shown in the live view
+	frameSequence uint64
 
-	open.SetFilter(storage.NewExtensionFileFilter([]string{".png"}))
-	open.Show()
-}
+	// buttons for network cameras, and the stream latency of the current one
+	NetworkCameraContainer *fyne.Container
+	networkCameraButtons   map[int]*widget.Button
+	LatencyLabel           *widget.Label
+	latencyUpdateTime      time.Time
 
-func NewBackgroundPanel() *BackgroundPanel {
-	backgroundPanel := BackgroundPanel{}
-	loadButton := widget.NewButton("Load Background Image", func() {
-		backgroundPanel.OpenFileDialog()
-	})
-	dummyMat := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255.0, 255.0, 255.0, 255.0), 270, 480, gocv.MatTypeCV8UC3)
-	backgroundPanel.BackgroundImageMat = &dummyMat
-	img, err := backgroundPanel.BackgroundImageMat.ToImage()
-	if err != nil {
-		return nil
-	}
-	backgroundPanel.BackgroundImage = canvas.NewImageFromImage(img)
-	panelLayout := layout.NewVBoxLayout()
-	backgroundPanel.BackgroundImage.SetMinSize(fyne.NewSize(480, 270))
-	backgroundPanel.GenerateResizedBackground()
-	panelLayout.Layout([]fyne.CanvasObject{loadButton, backgroundPanel.BackgroundImage}, fyne.NewSize(480, 300))
-	container := fyne.NewContainerWithLayout(panelLayout, loadButton, backgroundPanel.BackgroundImage)
-	backgroundPanel.Container = container
+	// contextual panel
+	ContextPane *fyne.Container
 
-	return &backgroundPanel
+	ProjectPanel       *Gallery
+	ChromaPanel        *ChromaPanel
+	DifferenceKeyPanel *DifferenceKeyPanel
+	LumaKeyPanel       *LumaKeyPanel
+	GarbageMattePanel  *GarbageMattePanel
+	ZoomPanel          *ZoomPanel
+	BackgroundPanel    *BackgroundPanel
+	LayerPanel         *LayerPanel
+	CameraPanel        *CameraPanel
+	TimelapsePanel     *TimelapsePanel
+	OnionSkinPanel     *OnionSkinPanel
+	GuidesPanel        *GuidesPanel
+	ReferencePanel     *ReferencePanel
+	FilterPanel        *FilterPanel
+	LUTPanel           *LUTPanel
+	FlipControl        *FlipControl
 }
 
 type ProjectPanel struct {
@@ -161,29 +78,6 @@ type ProjectPanel struct {
 	Container *fyne.Container
 }
 
-func (c *TopComponent) saveCanvasImage(canvasImage *canvas.Image, absImageFilepath string) (*image.Image, error) {
-	imageFile, err := os.Create(absImageFilepath)
-	if err != nil {
-		return nil, err
-	}
-	defer imageFile.Close()
-
-	imageBytes := canvasImage.Resource.Content()
-	img, _, err := image.Decode(bytes.NewReader(imageBytes))
-	if err != nil {
-		return nil, err
-	}
-	encoder := fastpng.Encoder{
-		CompressionLevel: fastpng.BestSpeed,
-	}
-	err = encoder.Encode(imageFile, img)
-	if err != nil {
-		return nil, err
-	}
-
-	return &img, nil
-}
-
 func (c *TopComponent) saveImage(img *image.Image, absImageFilepath string) error {
 	imageFile, err := os.Create(absImageFilepath)
 	if err != nil {
@@ -218,6 +112,13 @@ func (c *TopComponent) Snapshot() error {
 		return err
 	}
 
+	// the camera frames before keying, so snapshots can be rendered again with other settings
+	snapshotRawDir := fmt.Sprintf(`%s\snapshots\.raw`, projectName)
+	err = util.MkRelativeDir(snapshotRawDir)
+	if err != nil {
+		return err
+	}
+
 	newUUID, err := uuid.NewUUID()
 	if err != nil {
 		return err
@@ -230,206 +131,462 @@ func (c *TopComponent) Snapshot() error {
 
 	fullAbsImageFilePath := fmt.Sprintf(`%s\%s\%s.png`, baseDir, snapshotDir, newUUID.String())
 	fullThumbnailImageFilePath := fmt.Sprintf(`%s\%s\%s.png`, baseDir, snapshotThumbnailDir, newUUID.String())
+	fullRawImageFilePath := fmt.Sprintf(`%s\%s\%s.png`, baseDir, snapshotRawDir, newUUID.String())
+
+	// the other angles are read alongside the main camera so they show the same moment
+	angleFramesChan := make(chan map[int]*gocv.Mat, 1)
+	angleCameraIDs := angleCameras()
+	go func() {
+		angleFramesChan <- backend.ReadCameras(angleCameraIDs)
+		close(angleFramesChan)
+	}()
+	// the angle frames are closed however the snapshot ends, an early return waits for them to be read first
+	var angleFrames map[int]*gocv.Mat
+	defer func() {
+		if frames, ok := <-angleFramesChan; ok {
+			angleFrames = frames
+		}
+		for _, angleFrame := range angleFrames {
+			angleFrame.Close()
+		}
+	}()
 
-	img, err := c.saveCanvasImage(c.WebcamImage, fullAbsImageFilePath)
+	rawMat := gocv.NewMat()
+	defer rawMat.Close()
+	settings := backend.Backend.CurrentSettings()
+	if settings.SnapshotFrames > 1 {
+		err = c.captureStackedFrame(settings.SnapshotFrames, settings.SnapshotStacking, &rawMat)
+	} else {
+		err = c.captureFrame(&rawMat)
+	}
 	if err != nil {
 		return err
 	}
-	srcMat, err := gocv.ImageToMatRGB(*img)
+	renderSettings := settings.RenderSettings(c.keyMode(), settings.Framing)
+	srcMat := backend.RenderFrame(rawMat, AnimationFilmStripComponent.InsertionIndex(), renderSettings, liveSources{c})
+	defer srcMat.Close()
+	img, err := srcMat.ToImage()
+	if err != nil {
+		return err
+	}
+	err = c.saveImage(&img, fullAbsImageFilePath)
 	if err != nil {
 		return err
 	}
-	defer srcMat.Close()
 
 	thumbnailMat := gocv.NewMat()
 	defer thumbnailMat.Close()
 
-	gocv.Resize(srcMat, &thumbnailMat, image.Pt(thumbnailWidth, thumbnailHeight), 0, 0, gocv.InterpolationLinear)
+	thumbnailFitWidth, thumbnailFitHeight := backend.CurrentResolution().FitInto(thumbnailWidth, thumbnailHeight)
+	gocv.Resize(srcMat, &thumbnailMat, image.Pt(thumbnailFitWidth, thumbnailFitHeight), 0, 0, gocv.InterpolationLinear)
 	thumbnailImage, err := thumbnailMat.ToImage()
 	err = c.saveImage(&thumbnailImage, fullThumbnailImageFilePath)
 	if err != nil {
 		return err
 	}
 
+	rawImage, err := rawMat.ToImage()
+	if err != nil {
+		return err
+	}
+	err = c.saveImage(&rawImage, fullRawImageFilePath)
+	if err != nil {
+		return err
+	}
+
+	newFrame := backend.Frame{Filename: fullAbsImageFilePath, ThumbnailFilename: fullThumbnailImageFilePath,
+		RawFilename: fullRawImageFilePath, RenderID: backend.Backend.AddRender(renderSettings)}
+	angleFrames = <-angleFramesChan
+	for _, deviceID := range angleCameraIDs {
+		angleFrame, ok := angleFrames[deviceID]
+		if !ok {
+			log.Printf("no frame from %s for this snapshot", cameraName(deviceID))
+			continue
+		}
+		angleImageFilePath := fmt.Sprintf(`%s\%s\%s-%d.png`, baseDir, snapshotDir, newUUID.String(), deviceID)
+		angleThumbnailFilePath := fmt.Sprintf(`%s\%s\%s-%d.png`, baseDir, snapshotThumbnailDir, newUUID.String(), deviceID)
+		err = c.saveFrameImage(angleFrame, angleImageFilePath, angleThumbnailFilePath)
+		if err != nil {
+			log.Printf("error saving %s angle: %s", cameraName(deviceID), err.Error())
+			continue
+		}
+		newFrame.Angles = append(newFrame.Angles, &backend.Angle{DeviceID: deviceID, Filename: angleImageFilePath, ThumbnailFilename: angleThumbnailFilePath,
+			NetworkURL: backend.NetworkCameraURL(deviceID)})
+	}
+
 	cursor := AnimationFilmStripComponent.Cursor
 	log.Printf("cursor=%d", cursor)
-	if cursor == -1 || cursor == len(backend.Backend.Frames)-1 {
-		backend.Backend.Append(&backend.Frame{Filename: fullAbsImageFilePath, ThumbnailFilename: fullThumbnailImageFilePath})
+	// the intervalometer snapshots from its own goroutine, so the frames are counted under the lock
+	if cursor == -1 || cursor == len(backend.Backend.FrameList())-1 {
+		backend.Backend.Append(&newFrame)
 	} else {
-		backend.Backend.InsertAt(cursor+1, &backend.Frame{Filename: fullAbsImageFilePath, ThumbnailFilename: fullThumbnailImageFilePath})
+		backend.Backend.InsertAt(cursor+1, &newFrame)
+		c.ZoomPanel.refreshKeyframes() // the camera move's keyframes after it moved along
 	}
 
-	backend.Backend.Save()
+	// the frame is taken either way, a time-lapse shouldn't count it as missed
+	err = backend.Backend.Save()
+	if err != nil {
+		log.Printf("error saving project: %s", err.Error())
+	}
 	canvas.Refresh(c.WebcamImage)
 
 	return nil
 }
 
+// angleCameras returns the open cameras, other than the current one, that the project captures with every snapshot
+func angleCameras() []int {
+	deviceIDs := make([]int, 0)
+	openCameraIDs := backend.OpenCameraIDs()
+	for _, deviceID := range backend.Backend.CurrentSettings().AngleCameraIDs() {
+		if deviceID != backend.CurrentWebcamID && util.ContainsInt(openCameraIDs, deviceID) {
+			deviceIDs = append(deviceIDs, deviceID)
+		}
+	}
+	return deviceIDs
+}
+
+// saveFrameImage saves a frame together with its thumbnail
+func (c *TopComponent) saveFrameImage(frame *gocv.Mat, absImageFilepath string, absThumbnailFilepath string) error {
+	img, err := frame.ToImage()
+	if err != nil {
+		return err
+	}
+	err = c.saveImage(&img, absImageFilepath)
+	if err != nil {
+		return err
+	}
+
+	thumbnailMat := gocv.NewMat()
+	defer thumbnailMat.Close()
+	thumbnailFitWidth, thumbnailFitHeight := backend.CurrentResolution().FitInto(thumbnailWidth, thumbnailHeight)
+	gocv.Resize(*frame, &thumbnailMat, image.Pt(thumbnailFitWidth, thumbnailFitHeight), 0, 0, gocv.InterpolationLinear)
+	thumbnailImage, err := thumbnailMat.ToImage()
+	if err != nil {
+		return err
+	}
+	return c.saveImage(&thumbnailImage, absThumbnailFilepath)
+}
+
+// captureFrame waits for the first camera frame read after the snapshot was requested, so the snapshot
+// shows the scene as it is now rather than a frame the live view may have shown a moment ago
+func (c *TopComponent) captureFrame(dst *gocv.Mat) error {
+	_, ok := backend.NextFrame(backend.FrameSequence(), dst, snapshotFrameTimeout)
+	if !ok {
+		return errors.New("the camera is not delivering frames")
+	}
+	return nil
+}
+
+// captureStackedFrame takes frameCount consecutive frames from the camera and stacks them to reduce noise
+func (c *TopComponent) captureStackedFrame(frameCount int, mode backend.StackMode, dst *gocv.Mat) error {
+	defer util.LogPerf(fmt.Sprintf("TopComponent.captureStackedFrame(%d)", frameCount), time.Now())
+	frames := make([]gocv.Mat, 0, frameCount)
+	defer func() {
+		for _, frame := range frames {
+			frame.Close()
+		}
+	}()
+	sequence := backend.FrameSequence()
+	for len(frames) < frameCount {
+		frame := gocv.NewMat()
+		var ok bool
+		sequence, ok = backend.NextFrame(sequence, &frame, snapshotFrameTimeout)
+		if !ok {
+			frame.Close()
+			return fmt.Errorf("camera delivered only %d of %d frames", len(frames), frameCount)
+		}
+		frames = append(frames, frame)
+	}
+	return backend.StackFrames(frames, mode, dst)
+}
+
+// keyMode is the key snapshots are rendered with in the current capture mode
+func (c *TopComponent) keyMode() backend.KeyMode {
+	switch c.CaptureMode {
+	case CaptureModeChromaKey:
+		return backend.KeyChroma
+	case CaptureModeDifferenceKey:
+		return backend.KeyDifference
+	case CaptureModeLumaKey:
+		return backend.KeyLuma
+	}
+	return backend.KeyNone
+}
+
+// uncheckKeyToggles turns off the toggles of all keys except the one in use, only one key applies at a time.
+// The toggles' callbacks don't fire, the caller sets the capture mode.
+func (c *TopComponent) uncheckKeyToggles(except *widget.Check) {
+	for _, toggle := range []*widget.Check{c.ChromaPanel.ChromaFilterToggle, c.DifferenceKeyPanel.KeyToggle, c.LumaKeyPanel.KeyToggle} {
+		if toggle != except && toggle.Checked {
+			toggle.Checked = false
+			toggle.Refresh()
+		}
+	}
+}
+
 func (c *TopComponent) SetCaptureMode(mode CaptureMode) {
+	keyed := c.keyMode() != backend.KeyNone
 	c.CaptureMode = mode
+	if c.LayerPanel != nil && keyed != (c.keyMode() != backend.KeyNone) {
+		c.LayerPanel.Refresh() // layers behind the subject show only through a key
+	}
 }
 
+// ReadWebCam takes the next frame from the capture goroutine, waiting at most one capture loop period for it
 func (c *TopComponent) ReadWebCam(sourceMat *gocv.Mat) bool {
-	ok := backend.CurrentCamera().Read(sourceMat)
+	sequence, ok := backend.NextFrame(c.frameSequence, sourceMat, time.Duration(captureLoopSleepTime)*time.Millisecond)
 	if !ok {
 		return false
 	}
+	c.frameSequence = sequence
+	c.updateLatencyLabel()
+	if c.cameraDisconnected {
+		c.HideCameraDisconnected()
+	}
+	return true
+}
 
-	return !sourceMat.Empty()
+// handleReadFailure shows the disconnected state once the capture goroutine has given up on the camera.
+// Reconnecting happens in the background and the next frame clears the overlay.
+func (c *TopComponent) handleReadFailure() {
+	deviceID := backend.CurrentWebcamID
+	if deviceID < 0 {
+		c.ShowCameraDisconnected("No camera found. Please connect a camera.")
+		return
+	}
+	if backend.IsCameraLost() {
+		c.ShowCameraDisconnected(fmt.Sprintf("%s disconnected. Reconnecting...", cameraName(deviceID)))
+	}
+}
+
+func (c *TopComponent) ShowCameraDisconnected(message string) {
+	if c.cameraDisconnected && c.DisconnectedText.Text == message {
+		return
+	}
+	c.cameraDisconnected = true
+	c.DisconnectedText.Text = message
+	c.DisconnectedText.Refresh()
+	c.DisconnectedOverlay.Show()
+	c.WebcamImageContainer.Refresh()
+}
+
+func (c *TopComponent) HideCameraDisconnected() {
+	c.cameraDisconnected = false
+	c.DisconnectedOverlay.Hide()
+	c.WebcamImageContainer.Refresh()
 }
 
 func (c *TopComponent) captureLoopSleep() {
 	time.Sleep(time.Duration(captureLoopSleepTime) * time.Millisecond)
 }
 
-func (c *TopComponent) applyZoom(sourceMat *gocv.Mat) ([]byte, error) {
-	factor := c.ZoomPanel.ZoomSlider.Value
-	xMax := factor * config.WebcamCaptureWidth
-	yMax := factor * config.WebcamCaptureHeight
-	xOffset := (xMax - config.WebcamCaptureWidth) / 2
-	yOffset := (yMax - config.WebcamCaptureHeight) / 2
-	gocv.Resize(*sourceMat, sourceMat, image.Pt(0, 0), factor, factor, gocv.InterpolationLanczos4)
-	final := sourceMat.Region(image.Rect(int(xOffset), int(yOffset), int(xOffset+config.WebcamCaptureWidth), int(yOffset+config.WebcamCaptureHeight)))
-	defer final.Close()
-	return gocv.IMEncode(gocv.PNGFileExt, final)
+// zoomRect returns the region of a width x height frame that is visible at the current framing
+func (c *TopComponent) zoomRect(width int, height int) image.Rectangle {
+	return backend.Backend.CurrentSettings().Framing.Rect(width, height)
 }
 
-func (c *TopComponent) CaptureLoop() {
-	sourceMat := gocv.NewMat()
-	if ok := backend.CurrentCamera().Read(&sourceMat); !ok {
-		log.Printf("Device closed")
+// zoom scales the framed region of sourceMat up to the full frame size. The caller must close the result.
+func (c *TopComponent) zoom(sourceMat *gocv.Mat) gocv.Mat {
+	zoomed := gocv.NewMat()
+	backend.Zoom(*sourceMat, c.zoomRect(sourceMat.Cols(), sourceMat.Rows()), &zoomed)
+	return zoomed
+}
+
+// SetResolution switches the project's capture resolution and everything that is sized by it
+func (c *TopComponent) SetResolution(res backend.Resolution) {
+	log.Printf("switching capture resolution to %s", res)
+	currentCaptureMode := c.CaptureMode
+	c.SetCaptureMode(CaptureModeDisable)
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		settings.Resolution = res
+	})
+	backend.ApplyResolution()
+	c.BackgroundPanel.Invalidate()
+	c.BackgroundPanel.RefreshDisplay()
+	c.SetCaptureMode(currentCaptureMode)
+}
+
+func cameraName(deviceID int) string {
+	if backend.IsNetworkCamera(deviceID) {
+		return fmt.Sprintf("Network %d", deviceID-backend.NetworkCameraBaseID+1)
+	}
+	return fmt.Sprintf("Camera %d", deviceID+1)
+}
+
+// SelectCamera makes deviceID the camera used for live view and snapshots
+func (c *TopComponent) SelectCamera(deviceID int) {
+	currentCaptureMode := c.CaptureMode
+	c.SetCaptureMode(CaptureModeDisable)
+	_, _, err := backend.SwitchCamera(deviceID)
+	if err != nil {
+		if backend.IsNetworkCamera(deviceID) {
+			DisplayUserTip(fmt.Sprintf("Could not connect to %s. Will continue using previous camera.", backend.NetworkCameraURL(deviceID)))
+		} else {
+			DisplayUserTip("There is no camera at this slot. Will continue using previous camera. \nPlease connect a camera to this slot if you wish to use it.")
+		}
+	}
+	c.SetCaptureMode(currentCaptureMode)
+	c.updateLatencyLabel()
+	c.CameraPanel.RefreshAngleCameras()
+}
+
+func (c *TopComponent) OpenNetworkCameraDialog() {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("http://192.168.1.20:8080/video or rtsp://192.168.1.20:554/stream")
+	dialog.ShowCustomConfirm("Add Network Camera", "Connect", "Cancel", urlEntry, func(ok bool) {
+		if !ok {
+			return
+		}
+		deviceID, err := backend.AddNetworkCamera(urlEntry.Text)
+		if err != nil {
+			DisplayUserTip(err.Error())
+			return
+		}
+		streamURL := backend.NetworkCameraURL(deviceID)
+		backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+			if !util.ContainsString(settings.NetworkCameras, streamURL) {
+				settings.NetworkCameras = append(append([]string{}, settings.NetworkCameras...), streamURL)
+			}
+		})
+		SaveProjectSettings()
+		c.AddNetworkCameraButton(deviceID)
+		c.SelectCamera(deviceID)
+	}, *MocapApp.Window)
+}
+
+func (c *TopComponent) AddNetworkCameraButton(deviceID int) {
+	if c.networkCameraButtons == nil {
+		c.networkCameraButtons = map[int]*widget.Button{}
+	}
+	if c.networkCameraButtons[deviceID] != nil {
 		return
 	}
+	button := widget.NewButton(cameraName(deviceID), func() {
+		c.SelectCamera(deviceID)
+	})
+	c.networkCameraButtons[deviceID] = button
+	c.NetworkCameraContainer.AddObject(button)
+	c.NetworkCameraContainer.Refresh()
+}
 
-	sourceHsv := gocv.NewMat()
-	chromaKey := gocv.NewMat()
-	mask := gocv.NewMat()
-	inverseMask := gocv.NewMat()
+// updateLatencyLabel shows how long frames take to arrive from a network camera, at most once a second
+func (c *TopComponent) updateLatencyLabel() {
+	if time.Since(c.latencyUpdateTime) < time.Second {
+		return
+	}
+	c.latencyUpdateTime = time.Now()
+	deviceID := backend.CurrentWebcamID
+	if !backend.IsNetworkCamera(deviceID) {
+		c.LatencyLabel.SetText("")
+		return
+	}
+	c.LatencyLabel.SetText(fmt.Sprintf("Latency: %d ms", backend.CameraReadLatency(deviceID).Milliseconds()))
+}
+
+// ApplyProjectSettings pushes the settings of a freshly loaded project to the cameras and panels
+func (c *TopComponent) ApplyProjectSettings() {
+	for _, streamURL := range backend.Backend.CurrentSettings().NetworkCameras {
+		deviceID, err := backend.AddNetworkCamera(streamURL)
+		if err != nil {
+			log.Printf("skipping network camera: %s", err.Error())
+			continue
+		}
+		c.AddNetworkCameraButton(deviceID)
+	}
+	c.SetResolution(backend.CurrentResolution())
+	backend.ApplyCameraSettings(backend.CurrentCamera(), backend.Backend.CurrentSettings().Camera)
+	c.CameraPanel.Refresh()
+	c.ChromaPanel.ApplyProjectSettings()
+	c.DifferenceKeyPanel.ApplyProjectSettings()
+	c.LumaKeyPanel.Refresh()
+	c.GarbageMattePanel.ApplyProjectSettings()
+	c.TimelapsePanel.Refresh()
+	c.OnionSkinPanel.Refresh()
+	c.GuidesPanel.Refresh()
+	c.ReferencePanel.ApplyProjectSettings()
+	c.LayerPanel.ApplyProjectSettings()
+	c.BackgroundPanel.ApplyProjectSettings()
+	c.FilterPanel.Refresh()
+	c.LUTPanel.Refresh()
+	c.ZoomPanel.ApplyProjectSettings()
+}
 
-	final := gocv.NewMat()
+// applyChromaKey replaces the chroma key colored region of sourceMat with background, which has the same size.
+// With matteView it shows the key's matte instead.
+func (c *TopComponent) applyChromaKey(sourceMat gocv.Mat, background gocv.Mat, garbage backend.GarbageMask, matteView bool, final *gocv.Mat) {
+	backend.ApplyKey(sourceMat, background, backend.Backend.CurrentSettings().Key, garbage, matteView, final)
+}
 
-	defer sourceHsv.Close()
-	defer chromaKey.Close()
-	defer mask.Close()
-	defer inverseMask.Close()
+func (c *TopComponent) CaptureLoop() {
+	sourceMat := gocv.NewMat()
+	previewMat := gocv.NewMat()
+	rgbaMat := gocv.NewMat()
 
-	defer final.Close()
+	defer rgbaMat.Close()
+	defer previewMat.Close()
 	defer sourceMat.Close()
 
+	frameInterval := time.Second / previewFps
+	stats := previewStats{}
 	for { // start infinite capture loops
 		startTime := time.Now()
+		c.runIntervalometer()
+		if c.CaptureMode != CaptureModeDisable && backend.IsReconnecting(backend.CurrentWebcamID) {
+			c.handleReadFailure()
+			c.captureLoopSleep()
+			continue
+		}
 		switch c.CaptureMode {
-		case CaptureModeDisable:
-			// do nothing
-			//log.Printf("mode=CaptureModeDisable")
+		case CaptureModeDisable, CaptureModeColorPick:
+			// live view is frozen
 			c.captureLoopSleep()
 			continue
-		case CaptureModeNormal:
-			// normal capture mode. no filter
-			//log.Printf("mode=CaptureModeNormal")
+		case CaptureModeNormal, CaptureModeChromaKey, CaptureModeDifferenceKey, CaptureModeLumaKey:
+			// the key, if enabled, is applied by renderPreview
 			if !c.ReadWebCam(&sourceMat) {
-				log.Printf("Device closed or empty read from webcam")
-				c.captureLoopSleep()
+				c.handleReadFailure()
 				continue
 			}
-			//newStartTime := time.Now()
-			buf, err := c.applyZoom(&sourceMat)
-			if err != nil {
-				log.Printf("error: %s", err.Error())
-				c.captureLoopSleep()
-				continue
-			}
-			//log.Printf("IMEncode took %d ms", time.Since(newStartTime).Milliseconds())
-			c.WebcamImage.Resource = fyne.NewStaticResource("webcam", buf)
-			c.WebcamImageContainer.Objects[0] = c.WebcamImage
-		case CaptureModeColorPick:
-			// do nothing
-		case CaptureModeChromaKey:
-			// chroma key mode - apply chroma key filter and background image, if any
-			//log.Printf("mode=CaptureModeChromaKey")
-			if !c.ReadWebCam(&sourceMat) {
-				log.Printf("Device closed or empty read from webcam")
-				c.captureLoopSleep()
-				continue
-			}
-			// image processing should use HSV
-			gocv.CvtColor(sourceMat, &sourceHsv, gocv.ColorBGRToHSV)
-			nowR := c.ChromaPanel.RedSlider.Value
-			nowG := c.ChromaPanel.RedSlider.Value
-			nowB := c.ChromaPanel.BlueSlider.Value
-			nowF := c.ChromaPanel.FuzzSlider.Value
-
-			chromaKey = gocv.NewMatFromScalar(gocv.NewScalar(nowB, nowG, nowR, 255.0), gocv.MatTypeCV8UC3)
-			gocv.CvtColor(chromaKey, &chromaKey, gocv.ColorBGRToHSV)
-			keys := gocv.Split(chromaKey)
-			h := float64(keys[0].GetUCharAt(0, 0))
-			h1 := math.Max(h-nowF, 0)
-			h2 := math.Min(h+nowF, 179.0)
-
-			// split HSV lower bounds into H, S, V channels
-			lower := gocv.NewScalar(h1, 50.0, 100.0, 255.0)
-			upper := gocv.NewScalar(h2, 255.0, 255.0, 255.0)
-
-			gocv.InRangeWithScalar(sourceHsv, lower, upper, &mask)
-			gocv.BitwiseNot(mask, &inverseMask)
-
-			captureResult := gocv.NewMat()
-			backgroundResult := gocv.NewMat()
-
-			gocv.BitwiseAndWithMask(sourceHsv, sourceHsv, &captureResult, inverseMask)                                                         // green screened region deleted
-			gocv.BitwiseAndWithMask(*c.BackgroundPanel.BackgroundResizedHsv, *c.BackgroundPanel.BackgroundResizedHsv, &backgroundResult, mask) // green screened region remains
-			gocv.Add(backgroundResult, captureResult, &final)
-
-			// displayable image should be in BGR
-			gocv.CvtColor(final, &final, gocv.ColorHSVToBGR)
-
-			buf, err := c.applyZoom(&final)
-			if err != nil {
-				log.Printf("error: %s", err.Error())
-				c.captureLoopSleep()
-				continue
-			}
-			c.WebcamImage.Resource = fyne.NewStaticResource("webcam", buf)
-			c.WebcamImageContainer.Objects[0] = c.WebcamImage
-
-			err = backgroundResult.Close()
-			if err != nil {
-				log.Printf("error closing backgroundResult due to %s", err.Error())
-			}
-			err = captureResult.Close()
-			if err != nil {
-				log.Printf("error closing captureResult due to %s", err.Error())
-			}
-			err = chromaKey.Close()
-			if err != nil {
-				log.Printf("error closing chromaKey due to %s", err.Error())
-			}
+			c.renderPreview(&sourceMat, &previewMat)
+			c.showPreview(previewMat, &rgbaMat)
 		}
 
-		canvas.Refresh(c.WebcamImageContainer)
-		loopTiming := time.Since(startTime).Milliseconds()
-		if loopTiming > 200 {
-			log.Printf("warning. capture loop took too long: %d ms", loopTiming)
+		// sleep off whatever is left of the frame's time budget
+		renderTime := time.Since(startTime)
+		stats.record(renderTime, frameInterval)
+		if renderTime < frameInterval {
+			time.Sleep(frameInterval - renderTime)
 		}
-		c.captureLoopSleep()
 	} // infinite capture loop
 }
 
 type CaptureMode int
 
 const (
+	// how long the live view waits for a camera frame, and pauses while it is frozen
 	captureLoopSleepTime = 200
 
-	CaptureModeDisable = iota
+	// how long a snapshot waits for a fresh frame from the camera
+	snapshotFrameTimeout = 2 * time.Second
+)
+
+const (
+	CaptureModeDisable CaptureMode = iota
 	CaptureModeNormal
 	CaptureModeColorPick
 	CaptureModeChromaKey
+	CaptureModeDifferenceKey
+	CaptureModeLumaKey
 )
 
 func ExistingProjectTapHandler(projName string) error {
 	defer util.LogPerf("ExistingProjectTapHandler()", time.Now())
 	log.Printf("will load existing project %s", projName)
+	AnimationTopComponent.TimelapsePanel.Stop()
+	FlushProjectSettings()
 	err := backend.Backend.Load(projName)
+	AnimationTopComponent.ApplyProjectSettings()
 	AnimationFilmStripComponent.Tail()
 	AnimationFilmStripComponent.SyncToBackend()
 	UpdateMocapTitle()
@@ -439,33 +596,93 @@ func ExistingProjectTapHandler(projName string) error {
 func NewProjectTapHandler(name string) error {
 	defer util.LogPerf("NewProjectTapHandler()", time.Now())
 	log.Printf("will load new project %s", name)
+	AnimationTopComponent.TimelapsePanel.Stop()
 	backend.Backend.RemoveAll()
+	// settings are kept per project, a new one starts from the defaults rather than the project open before it
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		*settings = backend.NewProjectSettings()
+	})
+	AnimationTopComponent.ApplyProjectSettings()
 	AnimationFilmStripComponent.Tail()
 	AnimationFilmStripComponent.SyncToBackend()
 	UpdateMocapTitle()
 	return nil
 }
 
+// settings are saved once they stopped changing for this long, so dragging a slider doesn't rewrite the project, and
+// hold up snapshots, on every step
+const settingsSaveDelay = 500 * time.Millisecond
+
+// the save waiting for the settings to stop changing
+var settingsSave struct {
+	lock  sync.Mutex
+	timer *time.Timer
+}
+
+// SaveProjectSettings persists the current settings if a project is open, after settingsSaveDelay without further
+// changes
+func SaveProjectSettings() {
+	settingsSave.lock.Lock()
+	defer settingsSave.lock.Unlock()
+	if settingsSave.timer != nil {
+		settingsSave.timer.Stop()
+	}
+	settingsSave.timer = time.AfterFunc(settingsSaveDelay, saveProjectSettings)
+}
+
+// FlushProjectSettings saves settings still waiting to be saved right away, before another project is opened or the
+// app quits
+func FlushProjectSettings() {
+	settingsSave.lock.Lock()
+	pending := settingsSave.timer != nil && settingsSave.timer.Stop()
+	settingsSave.timer = nil
+	settingsSave.lock.Unlock()
+	if pending {
+		saveProjectSettings()
+	}
+}
+
+func saveProjectSettings() {
+	if backend.Backend.Name == "" {
+		return
+	}
+	err := backend.Backend.Save()
+	if err != nil {
+		log.Printf("error saving project settings: %s", err.Error())
+	}
+}
+
 func DisplayUserTip(text string) {
 	appWindow := *MocapApp.Window
 	dialog.NewInformation("Useful Tip", text, appWindow)
 }
 
 func NewTopComponent() *TopComponent {
-	webcamImage := canvas.Image{}
+	webcamImage := canvas.Image{FillMode: canvas.ImageFillContain}
 	webcamImage.SetMinSize(fyne.NewSize(config.WebcamDisplayWidth, config.WebcamDisplayHeight))
-	webcamImageContainer := fyne.NewContainerWithLayout(layout.NewMaxLayout(), &webcamImage)
+	disconnectedText := canvas.NewText("", color.White)
+	disconnectedText.Alignment = fyne.TextAlignCenter
+	disconnectedText.TextSize = 20
+	disconnectedShade := canvas.NewRectangle(color.RGBA{A: 192})
+	disconnectedOverlay := fyne.NewContainerWithLayout(layout.NewMaxLayout(), disconnectedShade,
+		fyne.NewContainerWithLayout(layout.NewCenterLayout(), disconnectedText))
+	disconnectedOverlay.Hide()
+	webcamImageContainer := fyne.NewContainerWithLayout(layout.NewMaxLayout(), &webcamImage, disconnectedOverlay)
 
 	component := TopComponent{
-		WebcamImage: &webcamImage,
+		WebcamImage:         &webcamImage,
+		DisconnectedOverlay: disconnectedOverlay,
+		DisconnectedText:    disconnectedText,
 	}
 	component.WebcamImageContainer = webcamImageContainer
 
 	leftLayout := layout.NewVBoxLayout()
 	snapshotButton := widget.NewButton("Snapshot", func() {
 		err := component.Snapshot()
-		if err != nil {
+		if err != nil && backend.Backend.Name == "" {
 			DisplayUserTip("Please create/open a project before taking snapshots.")
+		} else if err != nil {
+			DisplayUserTip(fmt.Sprintf("Snapshot failed: %s", err.Error()))
 		}
 		AnimationFilmStripComponent.Tail()
 		AnimationFilmStripComponent.SyncToBackend()
@@ -475,13 +692,7 @@ func NewTopComponent() *TopComponent {
 	for camID := 0; camID < config.MaxCameras; camID ++ {
 		pinnedCamID := camID
 		cameraButtons = append(cameraButtons, widget.NewButton(fmt.Sprintf("Camera %d", pinnedCamID +1), func() {
-			currentCaptureMode := component.CaptureMode
-			component.SetCaptureMode(CaptureModeDisable)
-			_, _, err := backend.SwitchCamera(pinnedCamID)
-			if err != nil {
-				DisplayUserTip("There is no camera at this slot. Will continue using previous camera. \nPlease connect a camera to this slot if you wish to use it.")
-			}
-			component.SetCaptureMode(currentCaptureMode)
+			component.SelectCamera(pinnedCamID)
 		}))
 	}
 	cameraButtonContainer := fyne.NewContainerWithLayout(layout.NewHBoxLayout())
@@ -501,7 +712,20 @@ Icons made by https://www.flaticon.com/authors/pixel-perfect
 Copyright (c) Luke Maung 2020`, config.Version))
 	}))
 
-	leftContainer := fyne.NewContainerWithLayout(leftLayout, webcamImageContainer, snapshotButton, cameraButtonContainer)
+	component.LatencyLabel = widget.NewLabel("")
+	component.NetworkCameraContainer = fyne.NewContainerWithLayout(layout.NewHBoxLayout(), widget.NewButton("Add Network Camera", func() {
+		component.OpenNetworkCameraDialog()
+	}))
+	networkCameraRow := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), component.NetworkCameraContainer, component.LatencyLabel)
+
+	// time-lapse tab content, its countdown sits under the snapshot button
+	timelapsePanel := NewTimelapsePanel()
+	component.TimelapsePanel = timelapsePanel
+
+	flipControl := NewFlipControl()
+	component.FlipControl = flipControl
+
+	leftContainer := fyne.NewContainerWithLayout(leftLayout, webcamImageContainer, snapshotButton, flipControl.Container, timelapsePanel.StatusLabel, cameraButtonContainer, networkCameraRow)
 
 	absBaseDir, err := util.GetMocapBaseDir()
 	if err != nil {
@@ -521,140 +745,60 @@ Copyright (c) Luke Maung 2020`, config.Version))
 	projectTabContent.AddObject(projectPanel.Container)
 	component.ProjectPanel = projectPanel
 
+	// camera tab content
+	cameraPanel := NewCameraPanel(&component)
+	component.CameraPanel = cameraPanel
+
 	// chroma key tab content
 	rightLayout := layout.NewCenterLayout()
 	component.ContextPane = fyne.NewContainerWithLayout(rightLayout)
 
-	chromaPanel := ChromaPanel{
-		ChromaFilterToggle: widget.NewCheck("", func(flag bool) {
-			if flag {
-				component.ChromaPanel.ColorPickerToggle.Checked = false
-				component.ChromaPanel.ColorPickerToggle.Refresh()
-				component.SetCaptureMode(CaptureModeChromaKey)
-			} else {
-				component.SetCaptureMode(CaptureModeNormal)
-			}
-		}),
-		ColorPickerToggle: widget.NewCheck("", func(flag bool) {
-			if flag {
-				component.ChromaPanel.ChromaFilterToggle.Checked = false
-				component.ChromaPanel.ChromaFilterToggle.Refresh()
-				component.SetCaptureMode(CaptureModeColorPick)
-				// convert webcam image to a hotimage
-				hotImage := NewHotImageFromCanvasImage(component.WebcamImage, true, config.WebcamDisplayWidth, config.WebcamDisplayHeight,
-					func(s string, event *fyne.PointEvent) {
-						x, y := event.Position.X, event.Position.Y
-						bufReader := bytes.NewReader(component.WebcamImage.Resource.Content())
-						img, err := fastpng.Decode(bufReader)
-						if err != nil {
-							log.Printf("error decoding webcam image: %s", err.Error())
-							return
-						}
-						tempMat, err := gocv.ImageToMatRGBA(img)
-						if err != nil {
-							log.Printf("error decoding webcam image: %s", err.Error())
-							return
-						}
-						buf, err := component.applyZoom(&tempMat)
-						tempMat.Close()
-						finalImg, err := fastpng.Decode(bytes.NewReader(buf))
-						clr := finalImg.At(x*config.CaptureToDisplayWidthRatio, y*config.CaptureToDisplayWidthRatio) // clicks are on canvas image, where the dimensions are smaller than the underlying capture image
-						r, g, b, a := clr.RGBA()
-						log.Printf("left-clicked on webcam at %#v. color=(%d, %d, %d, %d)", event.Position, r/0x101, g/0x101, b/0x101, a/0x101)
-
-						component.ChromaPanel.RedSlider.Value = float64(r / 0x101)
-						component.ChromaPanel.GreenSlider.Value = float64(g / 0x101)
-						component.ChromaPanel.BlueSlider.Value = float64(b / 0x101)
-						component.ChromaPanel.FuzzSlider.Value = 20
-						component.ChromaPanel.ColorPickerToggle.Checked = false
-						component.ChromaPanel.ChromaFilterToggle.Checked = true
-						component.ChromaPanel.PreviewColor.FillColor = clr
-						component.ChromaPanel.ChromaFilterToggle.Checked = true
-						component.ChromaPanel.ColorPickerToggle.Checked = false
-
-						component.ChromaPanel.RedSlider.Refresh()
-						component.ChromaPanel.GreenSlider.Refresh()
-						component.ChromaPanel.BlueSlider.Refresh()
-						component.ChromaPanel.FuzzSlider.Refresh()
-						component.ChromaPanel.ColorPickerToggle.Refresh()
-						component.ChromaPanel.ChromaFilterToggle.Refresh()
-						component.ChromaPanel.PreviewColor.Refresh()
-						component.ChromaPanel.ChromaFilterToggle.Refresh()
-						component.ChromaPanel.ColorPickerToggle.Refresh()
-
-						component.SetCaptureMode(CaptureModeChromaKey)
-					}, func(s string, event *fyne.PointEvent) {
-						log.Printf("right-clicked on webcam at %#v", event.Position)
-					})
-				component.WebcamImageContainer.Objects[0] = hotImage
-			} else {
-				component.SetCaptureMode(CaptureModeNormal)
-				component.WebcamImageContainer.Objects[0] = component.WebcamImage
-			}
-		}),
-		RedSlider:   widget.NewSlider(0, 255),
-		GreenSlider: widget.NewSlider(0, 255),
-		BlueSlider:  widget.NewSlider(0, 255),
-		FuzzSlider:  widget.NewSlider(0, 255),
-	}
-
-	chromaPanel.PreviewColor = canvas.NewRectangle(chromaPanel.GetChromaKey())
-	chromaPanel.PreviewColor.SetMinSize(fyne.NewSize(320, 36))
-
-	redLabel := widget.NewLabel("R (0)")
-	greenLabel := widget.NewLabel("G (0)")
-	blueLabel := widget.NewLabel("B (0)")
-	fuzzLabel := widget.NewLabel("Fuzz (0)")
-	chromaPanel.RedSlider.OnChanged = func(value float64) {
-		redLabel.SetText(fmt.Sprintf("R (%d)", int(value)))
-		chromaPanel.PreviewColor.FillColor = chromaPanel.GetChromaKey()
-		canvas.Refresh(chromaPanel.PreviewColor)
-	}
-	chromaPanel.GreenSlider.OnChanged = func(value float64) {
-		greenLabel.SetText(fmt.Sprintf("G (%d)", int(value)))
-		chromaPanel.PreviewColor.FillColor = chromaPanel.GetChromaKey()
-		canvas.Refresh(chromaPanel.PreviewColor)
-	}
-	chromaPanel.BlueSlider.OnChanged = func(value float64) {
-		blueLabel.SetText(fmt.Sprintf("B (%d)", int(value)))
-		chromaPanel.PreviewColor.FillColor = chromaPanel.GetChromaKey()
-		canvas.Refresh(chromaPanel.PreviewColor)
-	}
-	chromaPanel.FuzzSlider.OnChanged = func(value float64) {
-		fuzzLabel.SetText(fmt.Sprintf("Fuzz (%d)", int(value)))
-		chromaPanel.PreviewColor.FillColor = chromaPanel.GetChromaKey()
-		canvas.Refresh(chromaPanel.PreviewColor)
-	}
-	chromaToggleGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Apply Chroma Key Filter") , chromaPanel.ChromaFilterToggle)
-	pickerToggleGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), widget.NewLabel("Color Picker Mode"), chromaPanel.ColorPickerToggle)
-	redGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), redLabel, chromaPanel.RedSlider)
-	greenGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), greenLabel, chromaPanel.GreenSlider)
-	blueGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), blueLabel, chromaPanel.BlueSlider)
-	fuzzGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(), fuzzLabel, chromaPanel.FuzzSlider)
-	chromaTabContent := fyne.NewContainerWithLayout(layout.NewVBoxLayout(), chromaToggleGroup, pickerToggleGroup, redGroup, greenGroup, blueGroup, fuzzGroup, chromaPanel.PreviewColor)
-
-	chromaPanel.Container = chromaTabContent
-	component.ChromaPanel = &chromaPanel
+	chromaPanel := NewChromaPanel(&component)
+	chromaTabContent := chromaPanel.Container
+	component.ChromaPanel = chromaPanel
 	component.ContextPane = component.ChromaPanel.Container
 
+	// difference key tab content
+	differenceKeyPanel := NewDifferenceKeyPanel(&component)
+	component.DifferenceKeyPanel = differenceKeyPanel
+
+	// luma key tab content
+	lumaKeyPanel := NewLumaKeyPanel(&component)
+	component.LumaKeyPanel = lumaKeyPanel
+
+	// garbage matte tab content
+	garbageMattePanel := NewGarbageMattePanel(&component)
+	component.GarbageMattePanel = garbageMattePanel
+
 	// zoom panel
-	zoomLabel := widget.NewLabel("1.0")
-	zoomSlider := widget.NewSlider(1.0, 5.0)
-	zoomSlider.Step = 0.1
-	zoomSlider.OnChanged = func(value float64) {
-		text := fmt.Sprintf("%.1f", value)
-		zoomLabel.SetText(text)
-	}
-	zoomContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(), fyne.NewContainerWithLayout(layout.NewFormLayout(), zoomLabel, zoomSlider))
-	zoomPanel := ZoomPanel{
-		Container:  zoomContainer,
-		ZoomSlider: zoomSlider,
-	}
-	component.ZoomPanel = &zoomPanel
+	zoomPanel := NewZoomPanel(&component)
+	component.ZoomPanel = zoomPanel
+
+	// filter tab contents
+	filterPanel := NewFilterPanel()
+	component.FilterPanel = filterPanel
+
+	// colour grade tab contents
+	lutPanel := NewLUTPanel()
+	component.LUTPanel = lutPanel
+
+	// onion skin tab contents
+	onionSkinPanel := NewOnionSkinPanel()
+	component.OnionSkinPanel = onionSkinPanel
+
+	// guides tab contents
+	guidesPanel := NewGuidesPanel()
+	component.GuidesPanel = guidesPanel
+
+	// reference tab contents
+	referencePanel := NewReferencePanel()
+	component.ReferencePanel = referencePanel
 
 	// background tab contents
-	backgroundPanel := NewBackgroundPanel()
-	backgroundTabContent := fyne.NewContainer(backgroundPanel.Container)
+	layerPanel := NewLayerPanel(&component)
+	component.LayerPanel = layerPanel
+	backgroundPanel := NewBackgroundPanel(layerPanel)
+	backgroundTabContent := fyne.NewContainerWithLayout(layout.NewVBoxLayout(), backgroundPanel.Container, layerPanel.Container)
 	component.BackgroundPanel = backgroundPanel
 
 	// add all the tabs to tab container
@@ -664,30 +808,74 @@ Copyright (c) Luke Maung 2020`, config.Version))
 		Icon:    nil,
 		Content: projectTabContent,
 	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Camera",
+		Icon:    nil,
+		Content: cameraPanel.Container,
+	})
 	tabContainer.Append(&widget.TabItem{
 		Text:    "Chroma Key",
 		Icon:    nil,
 		Content: chromaTabContent,
 	})
-	//TODO: implement this some day
-	//tabContainer.Append(&widget.TabItem{
-	//	Text:    "Filter",
-	//	Icon:    nil,
-	//	Content: fyne.NewContainer(),
-	//})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Difference Key",
+		Icon:    nil,
+		Content: differenceKeyPanel.Container,
+	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Luma Key",
+		Icon:    nil,
+		Content: lumaKeyPanel.Container,
+	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Garbage Matte",
+		Icon:    nil,
+		Content: garbageMattePanel.Container,
+	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Filter",
+		Icon:    nil,
+		Content: filterPanel.Container,
+	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Colour Grade",
+		Icon:    nil,
+		Content: lutPanel.Container,
+	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Onion Skin",
+		Icon:    nil,
+		Content: onionSkinPanel.Container,
+	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Guides",
+		Icon:    nil,
+		Content: guidesPanel.Container,
+	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Reference",
+		Icon:    nil,
+		Content: referencePanel.Container,
+	})
 	tabContainer.Append(&widget.TabItem{
 		Text:    "Zoom",
 		Icon:    nil,
-		Content: zoomContainer,
+		Content: zoomPanel.Container,
 	})
 	tabContainer.Append(&widget.TabItem{
 		Text:    "Background",
 		Icon:    nil,
 		Content: backgroundTabContent,
 	})
+	tabContainer.Append(&widget.TabItem{
+		Text:    "Time-lapse",
+		Icon:    nil,
+		Content: timelapsePanel.Container,
+	})
 
 	rootLayout := layout.NewHBoxLayout()
-	rootLayout.Layout([]fyne.CanvasObject{leftContainer, tabContainer}, fyne.NewSize(config.WebcamCaptureWidth, config.WebcamDisplayHeight))
+	rootLayout.Layout([]fyne.CanvasObject{leftContainer, tabContainer}, fyne.NewSize(config.WindowWidth, config.WebcamDisplayHeight))
 	rootContainer := fyne.NewContainerWithLayout(rootLayout, leftContainer, tabContainer)
 
 	component.Container = rootContainer
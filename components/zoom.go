@@ -0,0 +1,307 @@
+package components
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"gocv.io/x/gocv"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"../backend"
+	"../config"
+)
+
+const (
+	// smaller drags on the live view are taken for clicks
+	framingMinDrag = 8
+	// a click this soon after a drag ends belongs to the drag
+	framingTapAfterDrag = 300 * time.Millisecond
+)
+
+var (
+	framingDragColor   = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	framingResultColor = color.RGBA{R: 255, G: 220, B: 0, A: 255}
+)
+
+// ZoomPanel frames snapshots on a region of the camera frame, zoomed in and panned off centre, and keyframes the
+// camera move exports follow
+type ZoomPanel struct {
+	Container *fyne.Container
+
+	ZoomControl        *SliderControl
+	PanXControl        *SliderControl
+	PanYControl        *SliderControl
+	DragToggle         *widget.Check
+	MoveToggle         *widget.Check
+	KeyframeEntry      *widget.Entry
+	KeyframesContainer *fyne.Container
+
+	component *TopComponent
+
+	// the rectangle being dragged on the live view, in live view pixels, outlined by the capture loop
+	lock        sync.Mutex
+	dragging    bool
+	dragStart   image.Point
+	dragEnd     image.Point
+	dragEndTime time.Time
+}
+
+// Refresh shows the project's framing, whether exports follow the camera move, and its keyframes
+func (p *ZoomPanel) Refresh() {
+	settings := backend.Backend.CurrentSettings()
+	p.ZoomControl.SetValue(settings.Framing.Zoom)
+	p.PanXControl.SetValue(settings.Framing.PanX * 100)
+	p.PanYControl.SetValue(settings.Framing.PanY * 100)
+	p.MoveToggle.Checked = settings.CameraMove.Enabled
+	p.MoveToggle.Refresh()
+	p.refreshKeyframes()
+}
+
+// refreshKeyframes rebuilds the keyframe list from the project settings
+func (p *ZoomPanel) refreshKeyframes() {
+	objects := make([]fyne.CanvasObject, 0)
+	for _, keyframe := range backend.Backend.CurrentSettings().CameraMove.Keyframes {
+		pinnedKeyframe := keyframe
+		label := widget.NewLabel(fmt.Sprintf("Frame %d: zoom %.1fx, pan %.0f%% / %.0f%%", keyframe.Frame+1,
+			keyframe.Framing.Zoom, keyframe.Framing.PanX*100, keyframe.Framing.PanY*100))
+		showButton := widget.NewButton("Show", func() {
+			p.setFraming(pinnedKeyframe.Framing)
+		})
+		removeButton := widget.NewButton("Remove", func() {
+			p.updateCameraMove(func(settings *backend.CameraMoveSettings) {
+				settings.Keyframes = backend.WithoutFramingKeyframe(settings.Keyframes, pinnedKeyframe.Frame)
+			})
+			p.refreshKeyframes()
+		})
+		objects = append(objects, fyne.NewContainerWithLayout(layout.NewHBoxLayout(), label, layout.NewSpacer(), showButton, removeButton))
+	}
+	if len(objects) == 0 {
+		objects = append(objects, widget.NewLabel("No keyframes. Frame the shot and set one above."))
+	}
+	p.KeyframesContainer.Objects = objects
+	p.KeyframesContainer.Refresh()
+}
+
+// ApplyProjectSettings stops framing on the live view and shows the new project's framing
+func (p *ZoomPanel) ApplyProjectSettings() {
+	p.StopDragging()
+	p.Refresh()
+}
+
+// setFraming frames snapshots and the live view with framing
+func (p *ZoomPanel) setFraming(framing backend.Framing) {
+	p.updateFraming(func(settings *backend.Framing) {
+		*settings = framing
+	})
+	p.Refresh()
+}
+
+// SetKeyframe keyframes the current framing on the frame entered, or on the selected frame if none is
+func (p *ZoomPanel) SetKeyframe() {
+	frame := -1
+	text := strings.TrimSpace(p.KeyframeEntry.Text)
+	if text != "" {
+		number, err := strconv.Atoi(text)
+		if err != nil || number < 1 {
+			DisplayUserTip("Keyframes are set on frame numbers starting from 1.")
+			return
+		}
+		frame = number - 1
+	} else if AnimationFilmStripComponent.Cursor >= 0 {
+		frame = AnimationFilmStripComponent.Cursor
+	}
+	if frame < 0 {
+		DisplayUserTip("Enter the frame number to keyframe, or select the frame first.")
+		return
+	}
+	keyframe := backend.FramingKeyframe{Frame: frame, Framing: backend.Backend.CurrentSettings().Framing}
+	p.updateCameraMove(func(settings *backend.CameraMoveSettings) {
+		settings.Keyframes = backend.WithFramingKeyframe(settings.Keyframes, keyframe)
+	})
+	p.refreshKeyframes()
+}
+
+// StartDragging lets a rectangle dragged on the live view frame snapshots on it, and a click centre the framing
+func (p *ZoomPanel) StartDragging() {
+	component := p.component
+	component.GarbageMattePanel.StopEditing()
+	if component.ChromaPanel.ColorPickerToggle.Checked {
+		component.ChromaPanel.ColorPickerToggle.SetChecked(false) // both pick points on the live view
+	}
+	hotImage := NewDraggableHotImageFromCanvasImage(component.WebcamImage, config.WebcamDisplayWidth, config.WebcamDisplayHeight,
+		func(s string, event *fyne.PointEvent) {
+			p.lock.Lock()
+			afterDrag := time.Since(p.dragEndTime) < framingTapAfterDrag
+			p.lock.Unlock()
+			if !afterDrag {
+				p.centreOn(displayToPreview(event.Position.X, event.Position.Y))
+			}
+		}, func(event *fyne.DragEvent) {
+			pt := displayToPreview(event.Position.X, event.Position.Y)
+			p.lock.Lock()
+			if !p.dragging {
+				p.dragging = true
+				p.dragStart = pt.Sub(image.Pt(event.DraggedX, event.DraggedY))
+			}
+			p.dragEnd = pt
+			p.lock.Unlock()
+		}, func() {
+			p.lock.Lock()
+			dragging := p.dragging
+			area := image.Rectangle{Min: p.dragStart, Max: p.dragEnd}.Canon()
+			p.dragging = false
+			p.dragEndTime = time.Now()
+			p.lock.Unlock()
+			if dragging && area.Dx() >= framingMinDrag && area.Dy() >= framingMinDrag {
+				p.setFraming(p.framingFor(area))
+			}
+		})
+	component.WebcamImageContainer.Objects[0] = hotImage
+	component.WebcamImageContainer.Refresh()
+}
+
+// StopDragging turns framing on the live view off, if it is on
+func (p *ZoomPanel) StopDragging() {
+	if !p.DragToggle.Checked {
+		return
+	}
+	p.DragToggle.Checked = false
+	p.DragToggle.Refresh()
+	p.restoreLiveView()
+}
+
+// restoreLiveView gives the live view back its plain image
+func (p *ZoomPanel) restoreLiveView() {
+	p.lock.Lock()
+	p.dragging = false
+	p.lock.Unlock()
+	p.component.WebcamImageContainer.Objects[0] = p.component.WebcamImage
+	p.component.WebcamImageContainer.Refresh()
+}
+
+// toFrame maps a live view pixel to the captured frame
+func (p *ZoomPanel) toFrame(pt image.Point) image.Point {
+	resolution := backend.CurrentResolution()
+	region := p.component.zoomRect(resolution.Width, resolution.Height)
+	size := previewSize()
+	return image.Pt(region.Min.X+pt.X*region.Dx()/size.X, region.Min.Y+pt.Y*region.Dy()/size.Y)
+}
+
+// framingFor is the framing that shows area of the live view
+func (p *ZoomPanel) framingFor(area image.Rectangle) backend.Framing {
+	resolution := backend.CurrentResolution()
+	rect := image.Rectangle{Min: p.toFrame(area.Min), Max: p.toFrame(area.Max)}
+	return backend.FramingFromRect(rect, resolution.Width, resolution.Height)
+}
+
+// centreOn pans the framing so the live view pixel pt is in the middle, as near as the frame edges allow
+func (p *ZoomPanel) centreOn(pt image.Point) {
+	resolution := backend.CurrentResolution()
+	p.setFraming(backend.FramingCentredOn(p.toFrame(pt), backend.Backend.CurrentSettings().Framing.Zoom, resolution.Width, resolution.Height))
+}
+
+// Apply outlines the rectangle being dragged on the live view frame dst, and the framing it will give
+func (p *ZoomPanel) Apply(dst *gocv.Mat) {
+	p.lock.Lock()
+	dragging := p.dragging
+	area := image.Rectangle{Min: p.dragStart, Max: p.dragEnd}.Canon()
+	p.lock.Unlock()
+	if !dragging || area.Dx() < framingMinDrag || area.Dy() < framingMinDrag {
+		return
+	}
+	gocv.Rectangle(dst, area, framingDragColor, 1)
+
+	// the framing keeps the frame's aspect ratio, mapped from the captured frame back to the live view
+	resolution := backend.CurrentResolution()
+	region := p.component.zoomRect(resolution.Width, resolution.Height)
+	result := p.framingFor(area).Rect(resolution.Width, resolution.Height)
+	size := image.Pt(dst.Cols(), dst.Rows())
+	toPreview := func(pt image.Point) image.Point {
+		return image.Pt((pt.X-region.Min.X)*size.X/region.Dx(), (pt.Y-region.Min.Y)*size.Y/region.Dy())
+	}
+	gocv.Rectangle(dst, image.Rectangle{Min: toPreview(result.Min), Max: toPreview(result.Max)}, framingResultColor, 1)
+}
+
+// updateFraming changes the project's framing and saves it
+func (p *ZoomPanel) updateFraming(update func(settings *backend.Framing)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.Framing)
+	})
+	SaveProjectSettings()
+}
+
+// updateCameraMove changes the project's camera move and saves it
+func (p *ZoomPanel) updateCameraMove(update func(settings *backend.CameraMoveSettings)) {
+	backend.Backend.UpdateSettings(func(settings *backend.ProjectSettings) {
+		update(&settings.CameraMove)
+	})
+	SaveProjectSettings()
+}
+
+func NewZoomPanel(component *TopComponent) *ZoomPanel {
+	panel := ZoomPanel{
+		KeyframeEntry:      widget.NewEntry(),
+		KeyframesContainer: fyne.NewContainerWithLayout(layout.NewVBoxLayout()),
+		component:          component,
+	}
+	panel.ZoomControl = NewSliderControl("Zoom", "%.1fx", 1.0, backend.MaxZoom, 0.1, func(value float64) {
+		panel.updateFraming(func(settings *backend.Framing) {
+			settings.Zoom = value
+		})
+	})
+	// pans are shown as percentages of the way from the centre to the frame edge
+	panel.PanXControl = NewSliderControl("Pan X", "%.0f%%", -100, 100, 1, func(value float64) {
+		panel.updateFraming(func(settings *backend.Framing) {
+			settings.PanX = value / 100
+		})
+	})
+	panel.PanYControl = NewSliderControl("Pan Y", "%.0f%%", -100, 100, 1, func(value float64) {
+		panel.updateFraming(func(settings *backend.Framing) {
+			settings.PanY = value / 100
+		})
+	})
+	panel.DragToggle = widget.NewCheck("", func(flag bool) {
+		if flag {
+			panel.StartDragging()
+			DisplayUserTip("Drag a rectangle on the live view to frame it, or click to centre the framing there.")
+		} else {
+			panel.restoreLiveView()
+		}
+	})
+	resetButton := widget.NewButton("Reset", func() {
+		panel.setFraming(backend.DefaultFraming)
+	})
+	panel.MoveToggle = widget.NewCheck("", func(flag bool) {
+		panel.updateCameraMove(func(settings *backend.CameraMoveSettings) {
+			settings.Enabled = flag
+		})
+	})
+	panel.KeyframeEntry.SetPlaceHolder("Frame (selected)")
+	keyframeButton := widget.NewButton("Set Keyframe", func() {
+		panel.SetKeyframe()
+	})
+
+	framingGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		panel.ZoomControl.Label, panel.ZoomControl.Slider,
+		panel.PanXControl.Label, panel.PanXControl.Slider,
+		panel.PanYControl.Label, panel.PanYControl.Slider,
+		widget.NewLabel("Frame on Live View"), fyne.NewContainerWithLayout(layout.NewHBoxLayout(), panel.DragToggle, resetButton))
+	moveGroup := fyne.NewContainerWithLayout(layout.NewFormLayout(),
+		widget.NewLabel("Camera Move on Export"), panel.MoveToggle,
+		widget.NewLabel("Keyframe"), fyne.NewContainerWithLayout(layout.NewHBoxLayout(), panel.KeyframeEntry, keyframeButton))
+	keyframesScroll := widget.NewVScrollContainer(panel.KeyframesContainer)
+	keyframesScroll.SetMinSize(fyne.NewSize(0, 200))
+	panel.Container = fyne.NewContainerWithLayout(layout.NewVBoxLayout(), framingGroup, moveGroup,
+		widget.NewLabel("Exports ease between keyframes, rendering each frame again from its raw camera frame."),
+		keyframesScroll)
+
+	panel.Refresh()
+
+	return &panel
+}
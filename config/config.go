@@ -5,14 +5,12 @@ const (
 
 	MocapDir = "Mocap Animation"
 
-	WebcamCaptureWidth  = 1280
-	WebcamCaptureHeight = 720
-
+	// live view and preview are letterboxed into this box; the actual display size is derived from the
+	// project's capture resolution
 	WebcamDisplayWidth  = 640
 	WebcamDisplayHeight = 360
 
-	CaptureToDisplayWidthRatio  = WebcamCaptureWidth / WebcamDisplayWidth
-	CaptureToDisplayHeightRatio = WebcamCaptureHeight / WebcamDisplayHeight
+	WindowWidth = 2 * WebcamDisplayWidth
 
 	MaxCameras = 7
 )
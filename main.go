@@ -27,6 +27,7 @@ type Mocap struct {
 
 func closeAllWebcams() {
 	components.AnimationTopComponent.SetCaptureMode(components.CaptureModeDisable)
+	backend.StopCapture()
 	for idx, cam := range backend.Cameras {
 		err := cam.Close()
 		if err != nil {
@@ -56,6 +57,7 @@ func startApp() {
 	components.UpdateMocapTitle()
 
 	window.ShowAndRun()
+	components.FlushProjectSettings()
 }
 
 func startFoo() {
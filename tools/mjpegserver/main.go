@@ -0,0 +1,119 @@
+// mjpegserver streams a still image, or a directory of images in name order, as MJPEG over HTTP.
+// It stands in for a phone or network camera when testing network camera sources:
+//
+//	go run tools/mjpegserver/main.go -source "C:\Users\me\Mocap Animation\myproject\snapshots" -fps 12
+//
+// then add http://localhost:8080/video as a network camera.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const boundary = "mocapframe"
+
+var (
+	source  = flag.String("source", "", "image file or directory of images to stream")
+	address = flag.String("address", ":8080", "address to listen on")
+	fps     = flag.Int("fps", 12, "frames per second")
+)
+
+func loadFrames(path string) ([][]byte, error) {
+	fileNames := []string{path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		fileInfos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		fileNames = fileNames[:0]
+		for _, fileInfo := range fileInfos {
+			extension := strings.ToLower(filepath.Ext(fileInfo.Name()))
+			if !fileInfo.IsDir() && (extension == ".png" || extension == ".jpg" || extension == ".jpeg") {
+				fileNames = append(fileNames, filepath.Join(path, fileInfo.Name()))
+			}
+		}
+		sort.Strings(fileNames)
+	}
+
+	frames := make([][]byte, 0)
+	for _, fileName := range fileNames {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(file)
+		file.Close()
+		if err != nil {
+			log.Printf("skipping %s: %s", fileName, err.Error())
+			continue
+		}
+		buf := bytes.Buffer{}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, buf.Bytes())
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no images found at %s", path)
+	}
+	return frames, nil
+}
+
+func main() {
+	flag.Parse()
+	if *source == "" || *fps <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	frames, err := loadFrames(*source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("loaded %d frames", len(frames))
+
+	http.HandleFunc("/video", func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("client %s connected", r.RemoteAddr)
+		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+boundary)
+		ticker := time.NewTicker(time.Second / time.Duration(*fps))
+		defer ticker.Stop()
+		for frameNum := 0; ; frameNum++ {
+			frame := frames[frameNum%len(frames)]
+			_, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", boundary, len(frame))
+			if err == nil {
+				_, err = w.Write(frame)
+			}
+			if err == nil {
+				_, err = w.Write([]byte("\r\n"))
+			}
+			if err != nil {
+				log.Printf("client %s disconnected", r.RemoteAddr)
+				return
+			}
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			<-ticker.C
+		}
+	})
+
+	log.Printf("streaming at http://localhost%s/video", *address)
+	log.Fatal(http.ListenAndServe(*address, nil))
+}
@@ -0,0 +1,21 @@
+package util
+
+// ContainsString reports whether item is in slice
+func ContainsString(slice []string, item string) bool {
+	for _, test := range slice {
+		if test == item {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsInt reports whether item is in slice
+func ContainsInt(slice []int, item int) bool {
+	for _, test := range slice {
+		if test == item {
+			return true
+		}
+	}
+	return false
+}